@@ -1,20 +1,47 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
 
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
 	"github.com/Naitik-ag/fleetintel-backend/internal/database"
 	"github.com/Naitik-ag/fleetintel-backend/internal/handlers"
 	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
 	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
 )
 
+type appHandlers struct {
+	tracking *handlers.TrackingHandler
+	agent    *handlers.AgentHandler
+	stream   *handlers.StreamHandler
+	order    *handlers.OrderHandler
+	geofence *handlers.GeofenceHandler
+	auth     *handlers.AuthHandler
+	tenant   *handlers.TenantHandler
+	status   *handlers.StatusHandler
+	shift    *handlers.ShiftHandler
+	earnings *handlers.EarningsHandler
+	rating   *handlers.RatingHandler
+	fleet    *handlers.FleetHandler
+	playback *handlers.PlaybackHandler
+	stop     *handlers.StopHandler
+	motion   *handlers.MotionHandler
+}
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
@@ -26,19 +53,129 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// The unique indexes on locations cannot be created while duplicates
+	// from retried uploads are still stored.
+	if _, err := database.RemoveDuplicateLocations(); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
 	err = database.AutoMigrate(
+		&models.Tenant{},
 		&models.Location{},
 		&models.DeliveryAgent{},
+		&models.Order{},
+		&models.Geofence{},
+		&models.GeofenceState{},
+		&models.GeofenceEvent{},
+		&models.MotionState{},
+		&models.MotionEvent{},
+		&models.AgentDistance{},
+		&models.User{},
+		&models.RefreshToken{},
+		&models.AgentCredential{},
+		&models.AgentStatusChange{},
+		&models.Shift{},
+		&models.PayRule{},
+		&models.EarningsEntry{},
+		&models.Payout{},
+		&models.Rating{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// point_id used to be unique per agent; it is now unique per tenant and
+	// agent.
+	if err := database.DropIndex(&models.Location{}, "idx_locations_agent_point"); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	tokens, err := auth.NewTokenManager(
+		os.Getenv("JWT_SECRET"),
+		durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		durationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+	)
+	if err != nil {
+		log.Fatal("Invalid auth configuration:", err)
+	}
+
+	// Repositories fail closed until scoped with ForTenant. Handlers scope them
+	// per request; the services that sweep or index every tenant's rows are
+	// given AllTenants explicitly.
 	locationRepo := repository.NewLocationRepository(database.GetDB())
 	agentRepo := repository.NewAgentRepository(database.GetDB())
+	orderRepo := repository.NewOrderRepository(database.GetDB())
+	geofenceRepo := repository.NewGeofenceRepository(database.GetDB())
+	motionRepo := repository.NewMotionRepository(database.GetDB())
+	distanceRepo := repository.NewDistanceRepository(database.GetDB())
+	userRepo := repository.NewUserRepository(database.GetDB())
+	credentialRepo := repository.NewCredentialRepository(database.GetDB())
+	tenantRepo := repository.NewTenantRepository(database.GetDB())
+	statusHistoryRepo := repository.NewStatusHistoryRepository(database.GetDB())
+	shiftRepo := repository.NewShiftRepository(database.GetDB())
+	earningsRepo := repository.NewEarningsRepository(database.GetDB())
+	ratingRepo := repository.NewRatingRepository(database.GetDB())
+
+	if err := tenantRepo.EnsureDefault(); err != nil {
+		log.Fatal("Failed to create default tenant:", err)
+	}
+
+	if err := ensureAdminUser(userRepo.ForTenant(models.DefaultTenantID)); err != nil {
+		log.Fatal("Failed to create initial user:", err)
+	}
+
+	hub := realtime.NewHub(64, 1000)
+	dispatchService := services.NewDispatchService(database.GetDB(), agentRepo, locationRepo, orderRepo)
+	geofenceService := services.NewGeofenceService(geofenceRepo.ForTenant(repository.AllTenants), hub)
+	motionConfig := services.DefaultMotionConfig()
+	if value := os.Getenv("MOTION_THRESHOLDS"); value != "" {
+		if err := motionConfig.ApplyThresholdsJSON([]byte(value)); err != nil {
+			log.Fatalf("Invalid MOTION_THRESHOLDS: %v", err)
+		}
+	}
+	motionService := services.NewMotionService(motionRepo, locationRepo, agentRepo, hub, motionConfig)
+	locationValidator := services.NewLocationValidator(services.DefaultValidationConfig())
+	distanceService := services.NewDistanceService(distanceRepo, locationRepo.ForTenant(repository.AllTenants), services.DefaultDistanceConfig())
+	statusReportService := services.NewStatusReportService(agentRepo, statusHistoryRepo)
+	earningsService := services.NewEarningsService(earningsRepo, agentRepo, distanceService)
+
+	fleetConfig := services.DefaultFleetSummaryConfig()
+	fleetConfig.CacheTTL = durationEnv("FLEET_SUMMARY_TTL", fleetConfig.CacheTTL)
+	fleetConfig.StaleAfter = durationEnv("STALE_LOCATION_AFTER", fleetConfig.StaleAfter)
+	fleetSummaryService := services.NewFleetSummaryService(agentRepo, locationRepo, orderRepo, fleetConfig)
+	playbackService := services.NewPlaybackService(agentRepo, locationRepo, services.DefaultDistanceConfig())
+	trackExportService := services.NewTrackExportService(agentRepo, locationRepo)
+
+	stopConfig := services.DefaultStopConfig()
+	stopConfig.RadiusM = floatEnv("STOP_RADIUS_M", stopConfig.RadiusM)
+	stopConfig.MinDuration = durationEnv("STOP_MIN_DURATION", stopConfig.MinDuration)
+	stopService := services.NewStopService(agentRepo, locationRepo, stopConfig)
 
-	trackingHandler := handlers.NewTrackingHandler(locationRepo)
-	agentHandler := handlers.NewAgentHandler(agentRepo)
+	authenticator := auth.NewAuthenticator(tokens, credentialRepo)
+
+	offlineConfig := services.DefaultOfflineConfig()
+	offlineConfig.SilenceWindow = durationEnv("OFFLINE_AFTER", offlineConfig.SilenceWindow)
+	offlineConfig.CheckInterval = durationEnv("OFFLINE_CHECK_INTERVAL", offlineConfig.CheckInterval)
+	offlineDetector := services.NewOfflineDetector(agentRepo.ForTenant(repository.AllTenants), statusHistoryRepo.ForTenant(repository.AllTenants), hub, offlineConfig)
+	shiftService := services.NewShiftService(shiftRepo, agentRepo, statusHistoryRepo, offlineDetector, services.DefaultShiftConfig())
+
+	h := appHandlers{
+		tracking: handlers.NewTrackingHandler(locationRepo, agentRepo, hub, locationValidator, geofenceService, offlineDetector, motionService),
+		agent:    handlers.NewAgentHandler(agentRepo, orderRepo, distanceService, earningsService, ratingRepo, hub, offlineDetector),
+		stream:   handlers.NewStreamHandler(hub),
+		order:    handlers.NewOrderHandler(orderRepo, agentRepo, dispatchService, earningsService),
+		geofence: handlers.NewGeofenceHandler(geofenceRepo, geofenceService),
+		auth:     handlers.NewAuthHandler(userRepo, credentialRepo, agentRepo, tenantRepo, tokens),
+		tenant:   handlers.NewTenantHandler(tenantRepo),
+		status:   handlers.NewStatusHandler(statusHistoryRepo, statusReportService),
+		shift:    handlers.NewShiftHandler(shiftRepo, agentRepo, shiftService, hub),
+		earnings: handlers.NewEarningsHandler(earningsRepo, agentRepo, earningsService),
+		rating:   handlers.NewRatingHandler(ratingRepo, orderRepo, agentRepo),
+		fleet:    handlers.NewFleetHandler(fleetSummaryService),
+		playback: handlers.NewPlaybackHandler(playbackService, trackExportService),
+		stop:     handlers.NewStopHandler(stopService),
+		motion:   handlers.NewMotionHandler(motionRepo, agentRepo),
+	}
 
 	app := fiber.New(fiber.Config{
 		AppName: "FleetIntel API",
@@ -47,18 +184,126 @@ func main() {
 	app.Use(logger.New())
 	app.Use(cors.New())
 
-	setupRoutes(app, trackingHandler, agentHandler)
+	setupRoutes(app, h, authenticator)
 
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8001"
 	}
 
-	log.Printf("Server is running on http://localhost:%s\n", port)
-	log.Fatal(app.Listen(":" + port))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	detectorDone := make(chan struct{})
+	go func() {
+		defer close(detectorDone)
+		offlineDetector.Run(ctx)
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server is running on http://localhost:%s\n", port)
+		serverErr <- app.Listen(":" + port)
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down...")
+	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+		log.Printf("Server shutdown: %v", err)
+	}
+	<-detectorDone
+	log.Println("Server stopped")
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+func floatEnv(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return f
+}
+
+// ensureAdminUser makes sure the default tenant has an active admin. On a
+// fresh deployment it creates one from ADMIN_EMAIL and ADMIN_PASSWORD; on a
+// deployment that predates roles it promotes the ADMIN_EMAIL user.
+func ensureAdminUser(userRepo *repository.UserRepository) error {
+	admins, err := userRepo.CountByRole(models.RoleAdmin)
+	if err != nil || admins > 0 {
+		return err
+	}
+
+	email := strings.ToLower(os.Getenv("ADMIN_EMAIL"))
+	password := os.Getenv("ADMIN_PASSWORD")
+
+	if email != "" {
+		existing, err := userRepo.FindByEmail(email)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil && existing.TenantID != models.DefaultTenantID {
+			log.Printf("ADMIN_EMAIL %s belongs to tenant %s, not promoting", email, existing.TenantID)
+			return nil
+		}
+
+		if existing != nil {
+			if err := userRepo.UpdateRole(existing.ID, models.RoleAdmin); err != nil {
+				return err
+			}
+			log.Printf("Promoted %s to admin", email)
+			return nil
+		}
+	}
+
+	if email == "" || password == "" {
+		log.Println("No admin exists yet; set ADMIN_EMAIL and ADMIN_PASSWORD to create one")
+		return nil
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = userRepo.Create(&models.User{
+		Email:        email,
+		Name:         "Administrator",
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		IsActive:     true,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Created initial admin %s", email)
+	return nil
 }
 
-func setupRoutes(app *fiber.App, trackingHandler *handlers.TrackingHandler, agentHandler *handlers.AgentHandler) {
+func setupRoutes(app *fiber.App, h appHandlers, authenticator *auth.Authenticator) {
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -68,19 +313,98 @@ func setupRoutes(app *fiber.App, trackingHandler *handlers.TrackingHandler, agen
 		})
 	})
 
-	api := app.Group("/api")
+	// Public auth routes must be registered before the authenticated /api
+	// group so they are matched first.
+	app.Post("/api/auth/login", h.auth.Login)
+	app.Post("/api/auth/refresh", h.auth.Refresh)
+	app.Post("/api/auth/logout", h.auth.Logout)
+
+	// Stream routes take the access token from the query string as well, so
+	// they are registered ahead of the /api group for the same reason.
+	app.Get("/api/tracking/stream", authenticator.StreamMiddleware, auth.Require(auth.PermTrackingRead), h.tracking.StreamLocations)
+	app.Get("/api/events", authenticator.StreamMiddleware, auth.Require(auth.PermEventsRead), h.stream.StreamEvents)
+
+	api := app.Group("/api", authenticator.Middleware)
+
+	authRoutes := api.Group("/auth")
+	authRoutes.Get("/me", h.auth.Me)
+	authRoutes.Post("/users", auth.Require(auth.PermUsersManage), h.auth.CreateUser)
+	authRoutes.Patch("/users/:id/role", auth.Allow(auth.PermUsersManage), h.auth.UpdateUserRole)
+
+	tenants := api.Group("/tenants", auth.RequirePlatformAdmin)
+	tenants.Post("/", h.tenant.CreateTenant)
+	tenants.Get("/", h.tenant.ListTenants)
 
 	agents := api.Group("/agents")
-	agents.Post("/", agentHandler.RegisterAgent)
-	agents.Get("/", agentHandler.ListAgents)
-	agents.Get("/:id", agentHandler.GetAgent)
-	agents.Put("/:id", agentHandler.UpdateAgent)
-	agents.Delete("/:id", agentHandler.DeleteAgent)
-	agents.Patch("/:id/status", agentHandler.UpdateAgentStatus)
-	agents.Get("/:id/stats", agentHandler.GetAgentStats)
+	agents.Post("/", auth.Require(auth.PermAgentsCreate), h.agent.RegisterAgent)
+	agents.Get("/", auth.Require(auth.PermAgentsRead), h.agent.ListAgents)
+	agents.Get("/:id", auth.Require(auth.PermAgentsRead), h.agent.GetAgent)
+	agents.Put("/:id", auth.Require(auth.PermAgentsUpdate), h.agent.UpdateAgent)
+	agents.Delete("/:id", auth.Require(auth.PermAgentsDeactivate), h.agent.DeleteAgent)
+	agents.Patch("/:id/status", auth.Require(auth.PermAgentsStatus), h.agent.UpdateAgentStatus)
+	agents.Get("/:id/stats", auth.Require(auth.PermAgentsRead), h.agent.GetAgentStats)
+	agents.Get("/:id/status-history", auth.Require(auth.PermAgentsRead), h.status.GetAgentStatusHistory)
+	agents.Get("/:id/status-report", auth.Require(auth.PermAgentsRead), h.status.GetAgentStatusReport)
+	agents.Get("/:id/shifts", auth.Require(auth.PermShiftsRead), h.shift.ListAgentShifts)
+	agents.Post("/:id/clock-in", auth.Require(auth.PermShiftsClock), h.shift.ClockIn)
+	agents.Post("/:id/clock-out", auth.Require(auth.PermShiftsClock), h.shift.ClockOut)
+	agents.Get("/:id/ratings", auth.Require(auth.PermRatingsRead), h.rating.ListAgentRatings)
+	agents.Get("/:id/earnings", auth.Require(auth.PermEarningsRead), h.earnings.GetEarnings)
+	agents.Post("/:id/earnings/adjustments", auth.Require(auth.PermEarningsManage), h.earnings.AddAdjustment)
+	agents.Get("/:id/earnings/payouts", auth.Require(auth.PermEarningsRead), h.earnings.ListPayouts)
+	agents.Post("/:id/earnings/payouts", auth.Require(auth.PermEarningsManage), h.earnings.CreatePayout)
+	agents.Get("/:id/earnings/payouts/:payoutId", auth.Require(auth.PermEarningsRead), h.earnings.GetPayout)
+	agents.Post("/:id/earnings/payouts/:payoutId/paid", auth.Require(auth.PermEarningsManage), h.earnings.MarkPayoutPaid)
+	agents.Get("/:id/geofence-events", auth.Require(auth.PermGeofencesRead), h.geofence.GetAgentGeofenceEvents)
+	agents.Get("/:id/motion", auth.Require(auth.PermTrackingRead), h.motion.GetAgentMotion)
+	agents.Post("/:id/credentials", auth.Require(auth.PermCredentialsManage), h.auth.CreateAgentCredential)
+	agents.Get("/:id/credentials", auth.Require(auth.PermCredentialsManage), h.auth.ListAgentCredentials)
+	agents.Delete("/:id/credentials/:credentialId", auth.Require(auth.PermCredentialsManage), h.auth.RevokeAgentCredential)
+
+	fleet := api.Group("/fleet")
+	fleet.Get("/summary", auth.Require(auth.PermAgentsRead), h.fleet.GetFleetSummary)
+	fleet.Get("/status-report", auth.Require(auth.PermAgentsRead), h.status.GetFleetStatusReport)
+
+	shifts := api.Group("/shifts")
+	shifts.Post("/", auth.Require(auth.PermShiftsWrite), h.shift.CreateShift)
+	shifts.Get("/", auth.Require(auth.PermShiftsRead), h.shift.ListShifts)
+	shifts.Get("/coverage", auth.Require(auth.PermShiftsRead), h.shift.GetCoverage)
+	shifts.Get("/:id", auth.Allow(auth.PermShiftsRead), h.shift.GetShift)
+	shifts.Delete("/:id", auth.Allow(auth.PermShiftsWrite), h.shift.CancelShift)
+
+	payRules := api.Group("/pay-rules", auth.Allow(auth.PermEarningsManage))
+	payRules.Post("/", h.earnings.CreatePayRule)
+	payRules.Get("/", h.earnings.ListPayRules)
+	payRules.Put("/:id", h.earnings.UpdatePayRule)
+	payRules.Delete("/:id", h.earnings.DeletePayRule)
 
 	tracking := api.Group("/tracking")
-	tracking.Post("/location", trackingHandler.UpdateLocation)
-	tracking.Get("/location/:id", trackingHandler.GetLiveLocation)
-	tracking.Get("/history/:id", trackingHandler.GetLocationHistory)
+	tracking.Post("/location", auth.Allow(auth.PermTrackingWrite), h.tracking.UpdateLocation)
+	tracking.Post("/locations/batch", auth.Allow(auth.PermTrackingWrite), h.tracking.UpdateLocationsBatch)
+	tracking.Post("/import/:id", auth.Require(auth.PermTrackingImport), h.tracking.ImportLocations)
+	tracking.Get("/location/:id", auth.Require(auth.PermTrackingRead), h.tracking.GetLiveLocation)
+	tracking.Get("/locations", auth.Require(auth.PermTrackingRead), h.tracking.GetLiveLocations)
+	tracking.Post("/locations/query", auth.Require(auth.PermTrackingRead), h.tracking.QueryLiveLocations)
+	tracking.Get("/history/:id", auth.Require(auth.PermTrackingRead), h.tracking.GetLocationHistory)
+	tracking.Get("/playback/:id", auth.Require(auth.PermTrackingRead), h.playback.GetPlayback)
+	tracking.Get("/export/:id", auth.Require(auth.PermTrackingRead), h.playback.ExportTrack)
+	tracking.Get("/stops/:id", auth.Require(auth.PermTrackingRead), h.stop.GetStops)
+	tracking.Get("/nearby", auth.Require(auth.PermTrackingRead), h.tracking.GetNearbyAgents)
+
+	orders := api.Group("/orders")
+	orders.Post("/", auth.Require(auth.PermOrdersCreate), h.order.CreateOrder)
+	orders.Get("/", auth.Require(auth.PermOrdersRead), h.order.ListOrders)
+	orders.Get("/:id", auth.Allow(auth.PermOrdersRead), h.order.GetOrder)
+	orders.Patch("/:id/status", auth.Allow(auth.PermOrdersAssign), h.order.UpdateOrderStatus)
+	orders.Post("/:id/dispatch", auth.Allow(auth.PermOrdersAssign), h.order.DispatchOrder)
+	orders.Post("/:id/rating", auth.Allow(auth.PermRatingsWrite), h.rating.SubmitRating)
+	orders.Get("/:id/rating", auth.Allow(auth.PermRatingsRead), h.rating.GetOrderRating)
+
+	geofences := api.Group("/geofences")
+	geofences.Post("/", auth.Require(auth.PermGeofencesWrite), h.geofence.CreateGeofence)
+	geofences.Get("/", auth.Require(auth.PermGeofencesRead), h.geofence.ListGeofences)
+	geofences.Get("/:id", auth.Allow(auth.PermGeofencesRead), h.geofence.GetGeofence)
+	geofences.Put("/:id", auth.Allow(auth.PermGeofencesWrite), h.geofence.UpdateGeofence)
+	geofences.Delete("/:id", auth.Allow(auth.PermGeofencesWrite), h.geofence.DeleteGeofence)
+	geofences.Get("/:id/events", auth.Allow(auth.PermGeofencesRead), h.geofence.GetGeofenceEvents)
 }
\ No newline at end of file
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const apiKeyScheme = "fik"
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// GenerateAPIKey returns a new device key of the form fik_<prefix>_<secret>
+// together with the prefix used to look it up and the hash to store.
+func GenerateAPIKey() (string, string, string, error) {
+	prefix, err := randomHex(6)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key := apiKeyScheme + "_" + prefix + "_" + secret
+	return key, prefix, HashToken(key), nil
+}
+
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyScheme || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func apiKeyMatches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	KindUser  = "user"
+	KindAgent = "agent"
+
+	principalKey = "principal"
+)
+
+// Principal is the authenticated caller: an operator signed in with a JWT
+// or an agent device using its API key. Every principal belongs to exactly
+// one tenant, and all data access is scoped to it.
+type Principal struct {
+	Kind         string
+	TenantID     string
+	UserID       uint
+	Email        string
+	Role         string
+	AgentID      string
+	CredentialID uint
+}
+
+func (p *Principal) IsAgent() bool {
+	return p.Kind == KindAgent
+}
+
+func FromContext(c *fiber.Ctx) *Principal {
+	principal, _ := c.Locals(principalKey).(*Principal)
+	return principal
+}
+
+// TenantID returns the authenticated caller's tenant.
+func TenantID(c *fiber.Ctx) string {
+	if principal := FromContext(c); principal != nil {
+		return principal.TenantID
+	}
+	return ""
+}
+
+type Authenticator struct {
+	tokens         *TokenManager
+	credentialRepo *repository.CredentialRepository
+}
+
+func NewAuthenticator(tokens *TokenManager, credentialRepo *repository.CredentialRepository) *Authenticator {
+	return &Authenticator{
+		tokens:         tokens,
+		credentialRepo: credentialRepo,
+	}
+}
+
+// Middleware authenticates the request from an "Authorization: Bearer" JWT
+// or an X-API-Key device key.
+func (a *Authenticator) Middleware(c *fiber.Ctx) error {
+	return a.authenticate(c, false)
+}
+
+// StreamMiddleware is Middleware for the WebSocket and SSE stream routes.
+// Browsers cannot set headers on those connections, so an access_token
+// query parameter is accepted as well. Anywhere else it would leave bearer
+// tokens in access logs and browser history.
+func (a *Authenticator) StreamMiddleware(c *fiber.Ctx) error {
+	return a.authenticate(c, true)
+}
+
+func (a *Authenticator) authenticate(c *fiber.Ctx, allowQuery bool) error {
+	if key := c.Get("X-API-Key"); key != "" {
+		return a.authenticateKey(c, key)
+	}
+
+	token := ""
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		scheme, value, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return unauthorized(c, "Authorization header must use the Bearer scheme")
+		}
+		token = strings.TrimSpace(value)
+	} else if allowQuery {
+		token = c.Query("access_token")
+	}
+
+	if token == "" {
+		return unauthorized(c, "Authentication required")
+	}
+
+	claims, err := a.tokens.ParseAccessToken(token)
+	if err != nil || claims.TenantID == "" {
+		return unauthorized(c, "Invalid or expired access token")
+	}
+
+	c.Locals(principalKey, &Principal{
+		Kind:     KindUser,
+		TenantID: claims.TenantID,
+		UserID:   claims.UserID,
+		Email:    claims.Email,
+		Role:     claims.Role,
+	})
+
+	return c.Next()
+}
+
+func (a *Authenticator) authenticateKey(c *fiber.Ctx, key string) error {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return unauthorized(c, "Invalid API key")
+	}
+
+	credential, err := a.credentialRepo.FindActiveByPrefix(prefix)
+	if err != nil {
+		log.Printf("Failed to look up API key: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to authenticate",
+		})
+	}
+
+	if credential == nil || !apiKeyMatches(key, credential.KeyHash) {
+		return unauthorized(c, "Invalid API key")
+	}
+
+	if err := a.credentialRepo.TouchLastUsed(credential.ID); err != nil {
+		log.Printf("Failed to record API key use: %v", err)
+	}
+
+	c.Locals(principalKey, &Principal{
+		Kind:         KindAgent,
+		TenantID:     credential.TenantID,
+		Role:         models.RoleAgent,
+		AgentID:      credential.AgentID,
+		CredentialID: credential.ID,
+	})
+
+	return c.Next()
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	return c.Status(401).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMiddlewareAccessTokenQuery(t *testing.T) {
+	tokens, err := NewTokenManager("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := tokens.IssueAccessToken(models.User{ID: 1, TenantID: "t1", Role: models.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := NewAuthenticator(tokens, nil)
+
+	tests := []struct {
+		name       string
+		middleware fiber.Handler
+		header     string
+		query      string
+		want       int
+	}{
+		{"header on a REST route", authenticator.Middleware, "Bearer " + token, "", 200},
+		{"query on a REST route", authenticator.Middleware, "", "?access_token=" + token, 401},
+		{"header on a stream route", authenticator.StreamMiddleware, "Bearer " + token, "", 200},
+		{"query on a stream route", authenticator.StreamMiddleware, "", "?access_token=" + token, 200},
+		{"bad query token on a stream route", authenticator.StreamMiddleware, "", "?access_token=nope", 401},
+		{"nothing on a stream route", authenticator.StreamMiddleware, "", "", 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", tt.middleware, func(c *fiber.Ctx) error {
+				return c.SendStatus(200)
+			})
+
+			req := httptest.NewRequest("GET", "/"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestScopeFor(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want Scope
+	}{
+		{models.RoleAdmin, PermUsersManage, ScopeAll},
+		{models.RoleAdmin, PermTrackingImport, ScopeAll},
+		{models.RoleAdmin, PermEarningsManage, ScopeAll},
+		{models.RoleDispatcher, PermOrdersAssign, ScopeAll},
+		{models.RoleDispatcher, PermAgentsStatus, ScopeAll},
+		{models.RoleDispatcher, PermAgentsCreate, ScopeNone},
+		{models.RoleDispatcher, PermTrackingImport, ScopeNone},
+		{models.RoleDispatcher, PermEarningsManage, ScopeNone},
+		{models.RoleViewer, PermTrackingRead, ScopeAll},
+		{models.RoleViewer, PermOrdersRead, ScopeAll},
+		{models.RoleViewer, PermAgentsStatus, ScopeNone},
+		{models.RoleViewer, PermEarningsRead, ScopeNone},
+		{models.RoleAgent, PermTrackingWrite, ScopeOwn},
+		{models.RoleAgent, PermShiftsRead, ScopeOwn},
+		{models.RoleAgent, PermRatingsRead, ScopeOwn},
+		{models.RoleAgent, PermTrackingRead, ScopeNone},
+		{models.RoleAgent, PermOrdersRead, ScopeNone},
+		{"unknown", PermAgentsRead, ScopeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+string(tt.perm), func(t *testing.T) {
+			if got := ScopeFor(tt.role, tt.perm); got != tt.want {
+				t.Errorf("ScopeFor(%q, %q) = %d, want %d", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	admin := &Principal{Kind: KindUser, Role: models.RoleAdmin}
+	viewer := &Principal{Kind: KindUser, Role: models.RoleViewer}
+	device := &Principal{Kind: KindAgent, Role: models.RoleAgent, AgentID: "agent-1"}
+	agentUser := &Principal{Kind: KindUser, Role: models.RoleAgent, AgentID: "agent-1"}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		perm      Permission
+		agentID   string
+		want      bool
+	}{
+		{"admin on any agent", admin, PermAgentsUpdate, "agent-2", true},
+		{"admin without agent", admin, PermAgentsRead, "", true},
+		{"viewer read", viewer, PermAgentsRead, "agent-2", true},
+		{"viewer write", viewer, PermAgentsUpdate, "agent-2", false},
+		{"device on itself", device, PermTrackingWrite, "agent-1", true},
+		{"device on another agent", device, PermTrackingWrite, "agent-2", false},
+		{"device without agent", device, PermShiftsRead, "", false},
+		{"device outside its role", device, PermOrdersRead, "agent-1", false},
+		{"own scope needs an agent principal", agentUser, PermTrackingWrite, "agent-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can(tt.perm, tt.agentID); got != tt.want {
+				t.Errorf("Can(%q, %q) = %v, want %v", tt.perm, tt.agentID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAndAllow(t *testing.T) {
+	device := &Principal{Kind: KindAgent, Role: models.RoleAgent, AgentID: "agent-1"}
+	viewer := &Principal{Kind: KindUser, Role: models.RoleViewer}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		guard     fiber.Handler
+		path      string
+		want      int
+	}{
+		{"require own agent", device, Require(PermShiftsRead), "/agent-1", 200},
+		{"require other agent", device, Require(PermShiftsRead), "/agent-2", 403},
+		{"require without principal", nil, Require(PermAgentsRead), "/agent-1", 403},
+		{"require all scope", viewer, Require(PermShiftsRead), "/agent-2", 200},
+		{"allow own scope on any id", device, Allow(PermShiftsRead), "/42", 200},
+		{"allow outside the role", device, Allow(PermOrdersRead), "/42", 403},
+		{"allow without principal", nil, Allow(PermOrdersRead), "/42", 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/:id", func(c *fiber.Ctx) error {
+				if tt.principal != nil {
+					c.Locals(principalKey, tt.principal)
+				}
+				return c.Next()
+			}, tt.guard, func(c *fiber.Ctx) error {
+				return c.SendStatus(200)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
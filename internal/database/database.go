@@ -49,6 +49,61 @@ func AutoMigrate(models ...interface{}) error {
 	return nil
 }
 
+// RemoveDuplicateLocations deletes repeated location rows so the unique
+// indexes on locations can be created. Device retries stored some points
+// more than once before ingestion was idempotent; of each (agent_id,
+// timestamp) group, and each (tenant_id, agent_id, point_id) group with a
+// point_id, the row with the lowest id is kept. It must run before
+// AutoMigrate and does nothing on a database without the table.
+func RemoveDuplicateLocations() (int64, error) {
+	migrator := DB.Migrator()
+	if !migrator.HasTable("locations") {
+		return 0, nil
+	}
+
+	result := DB.Exec(`DELETE FROM locations a USING locations b
+		WHERE a.agent_id = b.agent_id AND a.timestamp = b.timestamp AND a.id > b.id`)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to remove duplicate locations: %w", result.Error)
+	}
+	removed := result.RowsAffected
+
+	if migrator.HasColumn("locations", "point_id") {
+		// Rows from before tenants existed all land in the default tenant.
+		sameTenant := "TRUE"
+		if migrator.HasColumn("locations", "tenant_id") {
+			sameTenant = "a.tenant_id = b.tenant_id"
+		}
+
+		result = DB.Exec(`DELETE FROM locations a USING locations b
+			WHERE a.point_id IS NOT NULL AND ` + sameTenant + `
+			AND a.agent_id = b.agent_id AND a.point_id = b.point_id AND a.id > b.id`)
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to remove duplicate locations: %w", result.Error)
+		}
+		removed += result.RowsAffected
+	}
+
+	log.Printf("Removed %d duplicate locations", removed)
+	return removed, nil
+}
+
+// DropIndex removes an index that a model no longer declares. AutoMigrate
+// only adds indexes, so replaced ones have to be dropped explicitly.
+func DropIndex(model interface{}, name string) error {
+	migrator := DB.Migrator()
+	if !migrator.HasIndex(model, name) {
+		return nil
+	}
+
+	if err := migrator.DropIndex(model, name); err != nil {
+		return fmt.Errorf("failed to drop index %s: %w", name, err)
+	}
+
+	log.Printf("Dropped index %s", name)
+	return nil
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
\ No newline at end of file
@@ -0,0 +1,73 @@
+package geo
+
+import "math"
+
+const EarthRadiusMeters = 6371000.0
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// HaversineMeters returns the great-circle distance between two points.
+func HaversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Bounds is a latitude/longitude box. A box that crosses the antimeridian
+// is split in two longitude ranges: MinLng..MaxLng on the centre's side and
+// WrapMinLng..WrapMaxLng on the other, with Wrapped set.
+type Bounds struct {
+	MinLat, MaxLat         float64
+	MinLng, MaxLng         float64
+	Wrapped                bool
+	WrapMinLng, WrapMaxLng float64
+}
+
+// LngRanges returns the box's longitude ranges as [min, max] pairs.
+func (b Bounds) LngRanges() [][2]float64 {
+	ranges := [][2]float64{{b.MinLng, b.MaxLng}}
+	if b.Wrapped {
+		ranges = append(ranges, [2]float64{b.WrapMinLng, b.WrapMaxLng})
+	}
+	return ranges
+}
+
+// BoundingBox returns a box that contains every point within radiusM of the
+// centre. It is meant as a cheap pre-filter before an exact distance check.
+// A box reaching a pole spans every longitude.
+func BoundingBox(lat, lng, radiusM float64) Bounds {
+	dLat := radiusM / EarthRadiusMeters * 180 / math.Pi
+
+	b := Bounds{
+		MinLat: math.Max(-90, lat-dLat),
+		MaxLat: math.Min(90, lat+dLat),
+	}
+
+	dLng := 180.0
+	if cos := math.Cos(toRadians(lat)); cos > 1e-9 && b.MinLat > -90 && b.MaxLat < 90 {
+		dLng = math.Min(180, dLat/cos)
+	}
+
+	minLng, maxLng := lng-dLng, lng+dLng
+
+	switch {
+	case dLng >= 180:
+		b.MinLng, b.MaxLng = -180, 180
+	case minLng < -180:
+		b.MinLng, b.MaxLng = -180, maxLng
+		b.Wrapped, b.WrapMinLng, b.WrapMaxLng = true, minLng+360, 180
+	case maxLng > 180:
+		b.MinLng, b.MaxLng = minLng, 180
+		b.Wrapped, b.WrapMinLng, b.WrapMaxLng = true, -180, maxLng-360
+	default:
+		b.MinLng, b.MaxLng = minLng, maxLng
+	}
+
+	return b
+}
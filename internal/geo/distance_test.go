@@ -0,0 +1,117 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		radiusM  float64
+		ranges   [][2]float64
+		minLat   float64
+		maxLat   float64
+	}{
+		{
+			name:    "away from the antimeridian",
+			lat:     0,
+			lng:     10,
+			radiusM: 5000,
+			ranges:  [][2]float64{{9.955034, 10.044966}},
+			minLat:  -0.044966,
+			maxLat:  0.044966,
+		},
+		{
+			name:    "crossing east of 180",
+			lat:     0,
+			lng:     179.99,
+			radiusM: 5000,
+			ranges:  [][2]float64{{179.945034, 180}, {-180, -179.965034}},
+			minLat:  -0.044966,
+			maxLat:  0.044966,
+		},
+		{
+			name:    "crossing west of -180",
+			lat:     0,
+			lng:     -179.99,
+			radiusM: 5000,
+			ranges:  [][2]float64{{-180, -179.945034}, {179.965034, 180}},
+			minLat:  -0.044966,
+			maxLat:  0.044966,
+		},
+		{
+			name:    "reaching the north pole",
+			lat:     89.99,
+			lng:     45,
+			radiusM: 5000,
+			ranges:  [][2]float64{{-180, 180}},
+			minLat:  89.945034,
+			maxLat:  90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := BoundingBox(tt.lat, tt.lng, tt.radiusM)
+
+			if !near(b.MinLat, tt.minLat) || !near(b.MaxLat, tt.maxLat) {
+				t.Errorf("latitude = [%f, %f], want [%f, %f]", b.MinLat, b.MaxLat, tt.minLat, tt.maxLat)
+			}
+
+			ranges := b.LngRanges()
+			if len(ranges) != len(tt.ranges) {
+				t.Fatalf("longitude ranges = %v, want %v", ranges, tt.ranges)
+			}
+			for i := range ranges {
+				if !near(ranges[i][0], tt.ranges[i][0]) || !near(ranges[i][1], tt.ranges[i][1]) {
+					t.Errorf("longitude range %d = %v, want %v", i, ranges[i], tt.ranges[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBoundingBoxContainsCircle(t *testing.T) {
+	const radiusM = 20000
+
+	for _, centre := range [][2]float64{{51.5, 179.9}, {-33.9, -179.95}, {0, 180}, {60, 0}} {
+		b := BoundingBox(centre[0], centre[1], radiusM)
+
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			lat, lng := destination(centre[0], centre[1], bearing, radiusM*0.999)
+
+			if lat < b.MinLat || lat > b.MaxLat {
+				t.Errorf("centre %v bearing %.0f: latitude %f outside [%f, %f]", centre, bearing, lat, b.MinLat, b.MaxLat)
+			}
+
+			inside := false
+			for _, r := range b.LngRanges() {
+				if lng >= r[0] && lng <= r[1] {
+					inside = true
+				}
+			}
+			if !inside {
+				t.Errorf("centre %v bearing %.0f: longitude %f outside %v", centre, bearing, lng, b.LngRanges())
+			}
+		}
+	}
+}
+
+// destination returns the point distanceM away from the start along the
+// initial bearing, with the longitude normalised to [-180, 180].
+func destination(lat, lng, bearing, distanceM float64) (float64, float64) {
+	d := distanceM / EarthRadiusMeters
+	phi, lambda, theta := toRadians(lat), toRadians(lng), toRadians(bearing)
+
+	phi2 := math.Asin(math.Sin(phi)*math.Cos(d) + math.Cos(phi)*math.Sin(d)*math.Cos(theta))
+	lambda2 := lambda + math.Atan2(math.Sin(theta)*math.Sin(d)*math.Cos(phi), math.Cos(d)-math.Sin(phi)*math.Sin(phi2))
+
+	lng2 := math.Mod(lambda2*180/math.Pi+540, 360) - 180
+	return phi2 * 180 / math.Pi, lng2
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
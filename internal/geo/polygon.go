@@ -0,0 +1,25 @@
+package geo
+
+// PointInPolygon reports whether the point lies inside the polygon given as
+// [latitude, longitude] vertices, using ray casting. Polygons are treated as
+// planar, which is accurate enough for city-scale zones.
+func PointInPolygon(lat, lng float64, vertices [][2]float64) bool {
+	if len(vertices) < 3 {
+		return false
+	}
+
+	inside := false
+	j := len(vertices) - 1
+
+	for i := 0; i < len(vertices); i++ {
+		yi, xi := vertices[i][0], vertices[i][1]
+		yj, xj := vertices[j][0], vertices[j][1]
+
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+		j = i
+	}
+
+	return inside
+}
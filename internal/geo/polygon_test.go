@@ -0,0 +1,47 @@
+package geo
+
+import "testing"
+
+func TestPointInPolygon(t *testing.T) {
+	square := [][2]float64{{12.0, 77.0}, {12.0, 77.1}, {12.1, 77.1}, {12.1, 77.0}}
+
+	// An L shape: the square's lower-left three quarters.
+	lShape := [][2]float64{{12.0, 77.0}, {12.0, 77.1}, {12.05, 77.1}, {12.05, 77.05}, {12.1, 77.05}, {12.1, 77.0}}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		vertices [][2]float64
+		want     bool
+	}{
+		{"centre of square", 12.05, 77.05, square, true},
+		{"near a corner inside", 12.001, 77.001, square, true},
+		{"north of square", 12.2, 77.05, square, false},
+		{"east of square", 12.05, 77.2, square, false},
+		{"diagonal outside", 11.9, 76.9, square, false},
+		{"inside the L", 12.02, 77.08, lShape, true},
+		{"inside the L's upright", 12.08, 77.02, lShape, true},
+		{"in the L's notch", 12.08, 77.08, lShape, false},
+		{"degenerate polygon", 12.05, 77.05, [][2]float64{{12.0, 77.0}, {12.1, 77.1}}, false},
+		{"no vertices", 12.05, 77.05, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PointInPolygon(tt.lat, tt.lng, tt.vertices); got != tt.want {
+				t.Errorf("PointInPolygon(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPointInPolygonWindingOrder(t *testing.T) {
+	clockwise := [][2]float64{{12.0, 77.0}, {12.1, 77.0}, {12.1, 77.1}, {12.0, 77.1}}
+	counterClockwise := [][2]float64{{12.0, 77.0}, {12.0, 77.1}, {12.1, 77.1}, {12.1, 77.0}}
+
+	for _, p := range [][2]float64{{12.05, 77.05}, {12.2, 77.05}} {
+		if PointInPolygon(p[0], p[1], clockwise) != PointInPolygon(p[0], p[1], counterClockwise) {
+			t.Errorf("point %v: result depends on winding order", p)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package geo
+
+import "math"
+
+// Simplify reduces a path of [latitude, longitude] points with the
+// Douglas-Peucker algorithm and returns the indices of the points to keep,
+// in order. Points closer than toleranceM to the simplified line are
+// dropped; the first and last points are always kept.
+func Simplify(path [][2]float64, toleranceM float64) []int {
+	if len(path) < 3 || toleranceM <= 0 {
+		keep := make([]int, len(path))
+		for i := range path {
+			keep[i] = i
+		}
+		return keep
+	}
+
+	// Project onto a local plane in meters around the first point, which is
+	// accurate enough over the length of a route.
+	lat0 := toRadians(path[0][0])
+	cosLat := math.Cos(lat0)
+	xy := make([][2]float64, len(path))
+	for i, p := range path {
+		xy[i] = [2]float64{
+			toRadians(p[1]-path[0][1]) * cosLat * EarthRadiusMeters,
+			toRadians(p[0]-path[0][0]) * EarthRadiusMeters,
+		}
+	}
+
+	kept := make([]bool, len(path))
+	kept[0], kept[len(path)-1] = true, true
+
+	// Walk the ranges with an explicit stack so long tracks cannot exhaust
+	// the goroutine stack.
+	stack := [][2]int{{0, len(path) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDist := -1, toleranceM
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(xy[i], xy[first], xy[last]); d > maxDist {
+				farthest, maxDist = i, d
+			}
+		}
+
+		if farthest < 0 {
+			continue
+		}
+
+		kept[farthest] = true
+		stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+	}
+
+	keep := make([]int, 0, len(path))
+	for i, k := range kept {
+		if k {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
+
+// segmentDistance returns the planar distance from p to the segment a-b.
+func segmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/lengthSq))
+	}
+
+	return math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy)
+}
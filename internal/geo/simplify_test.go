@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// metersPath converts [east, north] offsets in meters from a point on the
+// equator into [latitude, longitude] points.
+func metersPath(offsets ...[2]float64) [][2]float64 {
+	const metersPerDegree = EarthRadiusMeters * math.Pi / 180
+
+	path := make([][2]float64, len(offsets))
+	for i, o := range offsets {
+		path[i] = [2]float64{o[1] / metersPerDegree, 30 + o[0]/metersPerDegree}
+	}
+	return path
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      [][2]float64
+		tolerance float64
+		want      []int
+	}{
+		{
+			name:      "empty",
+			path:      nil,
+			tolerance: 10,
+			want:      []int{},
+		},
+		{
+			name:      "two points",
+			path:      metersPath([2]float64{0, 0}, [2]float64{100, 0}),
+			tolerance: 10,
+			want:      []int{0, 1},
+		},
+		{
+			name:      "zero tolerance keeps everything",
+			path:      metersPath([2]float64{0, 0}, [2]float64{50, 1}, [2]float64{100, 0}),
+			tolerance: 0,
+			want:      []int{0, 1, 2},
+		},
+		{
+			name:      "straight line",
+			path:      metersPath([2]float64{0, 0}, [2]float64{25, 0}, [2]float64{50, 0}, [2]float64{75, 0}, [2]float64{100, 0}),
+			tolerance: 1,
+			want:      []int{0, 4},
+		},
+		{
+			name:      "jitter within tolerance",
+			path:      metersPath([2]float64{0, 0}, [2]float64{25, 3}, [2]float64{50, -4}, [2]float64{75, 2}, [2]float64{100, 0}),
+			tolerance: 5,
+			want:      []int{0, 4},
+		},
+		{
+			name:      "corner is kept",
+			path:      metersPath([2]float64{0, 0}, [2]float64{50, 0}, [2]float64{100, 0}, [2]float64{100, 50}, [2]float64{100, 100}),
+			tolerance: 5,
+			want:      []int{0, 2, 4},
+		},
+		{
+			name:      "detour is kept",
+			path:      metersPath([2]float64{0, 0}, [2]float64{40, 0}, [2]float64{50, 30}, [2]float64{60, 0}, [2]float64{100, 0}),
+			tolerance: 10,
+			want:      []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:      "round trip keeps the far end",
+			path:      metersPath([2]float64{0, 0}, [2]float64{50, 0}, [2]float64{100, 0}, [2]float64{50, 1}, [2]float64{0, 0}),
+			tolerance: 5,
+			want:      []int{0, 2, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Simplify(tt.path, tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyLongTrack(t *testing.T) {
+	offsets := make([][2]float64, 100000)
+	for i := range offsets {
+		offsets[i] = [2]float64{float64(i), float64(i % 2)}
+	}
+
+	keep := Simplify(metersPath(offsets...), 2)
+	if len(keep) != 2 || keep[0] != 0 || keep[1] != len(offsets)-1 {
+		t.Fatalf("kept %d points, want the two ends", len(keep))
+	}
+}
@@ -1,25 +1,55 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"strings"
+	"time"
 
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
 	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
 	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	defaultStatsRange = 30 * 24 * time.Hour
+	maxStatsRange     = 366 * 24 * time.Hour
+)
+
 type AgentHandler struct {
-	agentRepo *repository.AgentRepository
+	agentRepo       *repository.AgentRepository
+	orderRepo       *repository.OrderRepository
+	distanceService *services.DistanceService
+	earningsService *services.EarningsService
+	ratingRepo      *repository.RatingRepository
+	hub             *realtime.Hub
+	offlineDetector *services.OfflineDetector
 }
 
-func NewAgentHandler(agentRepo *repository.AgentRepository) *AgentHandler {
+func NewAgentHandler(agentRepo *repository.AgentRepository, orderRepo *repository.OrderRepository, distanceService *services.DistanceService, earningsService *services.EarningsService, ratingRepo *repository.RatingRepository, hub *realtime.Hub, offlineDetector *services.OfflineDetector) *AgentHandler {
 	return &AgentHandler{
-		agentRepo: agentRepo,
+		agentRepo:       agentRepo,
+		orderRepo:       orderRepo,
+		distanceService: distanceService,
+		earningsService: earningsService,
+		ratingRepo:      ratingRepo,
+		hub:             hub,
+		offlineDetector: offlineDetector,
 	}
 }
 
+func metersToKm(meters float64) float64 {
+	return math.Round(meters) / 1000
+}
+
 func (h *AgentHandler) RegisterAgent(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	var req models.AgentRequest
 
 	if err := c.BodyParser(&req); err != nil {
@@ -76,7 +106,12 @@ func (h *AgentHandler) RegisterAgent(c *fiber.Ctx) error {
 		IsActive:    true,
 	}
 
-	err := h.agentRepo.Create(&agent)
+	err := agentRepo.Create(&agent)
+	if errors.Is(err, repository.ErrAgentIDUnavailable) || errors.Is(err, repository.ErrAgentPhoneExists) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error":   "Failed to register agent",
@@ -86,6 +121,7 @@ func (h *AgentHandler) RegisterAgent(c *fiber.Ctx) error {
 
 	response := models.AgentResponse{
 		ID:          agent.ID,
+		TenantID:    agent.TenantID,
 		Name:        agent.Name,
 		Phone:       agent.Phone,
 		Email:       agent.Email,
@@ -104,6 +140,8 @@ func (h *AgentHandler) RegisterAgent(c *fiber.Ctx) error {
 }
 
 func (h *AgentHandler) GetAgent(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 
 	if agentID == "" {
@@ -112,7 +150,7 @@ func (h *AgentHandler) GetAgent(c *fiber.Ctx) error {
 		})
 	}
 
-	agent, err := h.agentRepo.FindByID(agentID)
+	agent, err := agentRepo.FindByID(agentID)
 	if err != nil {
 		log.Printf("Failed to fetch agent: %v", err)
 		return c.Status(500).JSON(fiber.Map{
@@ -129,6 +167,7 @@ func (h *AgentHandler) GetAgent(c *fiber.Ctx) error {
 
 	response := models.AgentResponse{
 		ID:          agent.ID,
+		TenantID:    agent.TenantID,
 		Name:        agent.Name,
 		Phone:       agent.Phone,
 		Email:       agent.Email,
@@ -146,6 +185,8 @@ func (h *AgentHandler) GetAgent(c *fiber.Ctx) error {
 }
 
 func (h *AgentHandler) ListAgents(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	limit := c.QueryInt("limit", 50)
 	page := c.QueryInt("page", 1)
 	status := c.Query("status", "")
@@ -162,7 +203,7 @@ func (h *AgentHandler) ListAgents(c *fiber.Ctx) error {
 
 	offset := (page - 1) * limit
 
-	agents, totalCount, err := h.agentRepo.FindAll(limit, offset, status)
+	agents, totalCount, err := agentRepo.FindAll(limit, offset, status)
 	if err != nil {
 		log.Printf("Failed to fetch agents: %v", err)
 		return c.Status(500).JSON(fiber.Map{
@@ -175,6 +216,7 @@ func (h *AgentHandler) ListAgents(c *fiber.Ctx) error {
 	for _, agent := range agents {
 		responses = append(responses, models.AgentResponse{
 			ID:          agent.ID,
+			TenantID:    agent.TenantID,
 			Name:        agent.Name,
 			Phone:       agent.Phone,
 			Email:       agent.Email,
@@ -208,6 +250,8 @@ func (h *AgentHandler) ListAgents(c *fiber.Ctx) error {
 }
 
 func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 	var req models.AgentRequest
 
@@ -249,7 +293,7 @@ func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.agentRepo.Update(agentID, updates)
+	err := agentRepo.Update(agentID, updates)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error":   "Failed to update agent",
@@ -257,13 +301,14 @@ func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
 		})
 	}
 
-	agent, _ := h.agentRepo.FindByID(agentID)
+	agent, _ := agentRepo.FindByID(agentID)
 
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Agent updated successfully",
 		"data": models.AgentResponse{
 			ID:          agent.ID,
+			TenantID:    agent.TenantID,
 			Name:        agent.Name,
 			Phone:       agent.Phone,
 			Email:       agent.Email,
@@ -277,6 +322,8 @@ func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
 }
 
 func (h *AgentHandler) UpdateAgentStatus(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 	var req models.AgentStatusUpdate
 
@@ -293,7 +340,13 @@ func (h *AgentHandler) UpdateAgentStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.agentRepo.UpdateStatus(agentID, req.Status)
+	if tooLong(req.Reason) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("reason must not exceed %d characters", maxTextLength),
+		})
+	}
+
+	previous, err := agentRepo.UpdateStatus(agentID, req.Status, statusTrigger(c, req.Reason))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error":   "Failed to update status",
@@ -301,6 +354,24 @@ func (h *AgentHandler) UpdateAgentStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	// Taken offline on purpose; new points must not bring it back online.
+	if req.Status == "offline" {
+		h.offlineDetector.Forget(agentID)
+	}
+
+	if previous != req.Status {
+		h.hub.Publish(realtime.Event{
+			Type:     realtime.EventStatus,
+			TenantID: auth.TenantID(c),
+			AgentID:  agentID,
+			Data: fiber.Map{
+				"agent_id":        agentID,
+				"previous_status": previous,
+				"status":          req.Status,
+			},
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Agent status updated successfully",
@@ -312,25 +383,48 @@ func (h *AgentHandler) UpdateAgentStatus(c *fiber.Ctx) error {
 }
 
 func (h *AgentHandler) DeleteAgent(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 
-	err := h.agentRepo.SoftDelete(agentID)
+	err := agentRepo.SoftDelete(agentID, statusTrigger(c, "agent deactivated"))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error":   "Failed to delete agent",
 			"details": err.Error(),
 		})
 	}
+
+	h.offlineDetector.Forget(agentID)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Agent deleted successfully",
 	})
 }
 
+// GetAgentStats returns the agent's lifetime stats and, when from or to is
+// given, the same stats for that window.
 func (h *AgentHandler) GetAgentStats(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+	ratingRepo := h.ratingRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 
-	agent, err := h.agentRepo.FindByID(agentID)
+	var window *models.StatsWindow
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, to, msg := parseTimeRange(c, defaultStatsRange, maxStatsRange)
+		if msg != "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+
+		window = &models.StatsWindow{From: from, To: to}
+	}
+
+	agent, err := agentRepo.FindByID(agentID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch agent",
@@ -343,13 +437,80 @@ func (h *AgentHandler) GetAgentStats(c *fiber.Ctx) error {
 		})
 	}
 
+	deliveries, err := orderRepo.CountDelivered(agentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch agent deliveries",
+		})
+	}
+
+	lifetime, err := h.distanceService.Lifetime(agentID)
+	if err != nil {
+		log.Printf("Failed to compute distance for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to compute agent distance",
+		})
+	}
+
+	if window != nil {
+		meters, err := h.distanceService.Window(agentID, window.From, window.To)
+		if err != nil {
+			log.Printf("Failed to compute window distance for agent %s: %v", agentID, err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute agent distance",
+			})
+		}
+		window.Distance = metersToKm(meters)
+
+		window.Earnings, err = h.earningsService.Total(auth.TenantID(c), agentID, window.From, window.To)
+		if err != nil {
+			log.Printf("Failed to compute window earnings for agent %s: %v", agentID, err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute agent earnings",
+			})
+		}
+
+		rating, _, err := ratingRepo.Average(agentID, window.From, window.To)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch agent ratings",
+			})
+		}
+		window.Rating = roundRating(rating)
+	}
+
+	earnings, err := h.earningsService.Total(auth.TenantID(c), agentID, time.Time{}, time.Time{})
+	if err != nil {
+		log.Printf("Failed to compute earnings for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to compute agent earnings",
+		})
+	}
+
+	rating, ratingCount, err := ratingRepo.Average(agentID, time.Time{}, time.Time{})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch agent ratings",
+		})
+	}
+
+	recentRating, _, err := ratingRepo.Average(agentID, time.Now().AddDate(0, 0, -defaultRecentDays), time.Time{})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch agent ratings",
+		})
+	}
+
 	stats := models.AgentStats{
 		AgentID:         agentID,
-		TotalDeliveries: 0,
-		TotalDistance:   0.0,
-		AverageRating:   0.0,
-		TotalEarnings:   0.0,
+		TotalDeliveries: int(deliveries),
+		TotalDistance:   metersToKm(lifetime),
+		AverageRating:   roundRating(rating),
+		RecentRating:    roundRating(recentRating),
+		RatingCount:     ratingCount,
+		TotalEarnings:   earnings,
 		ActiveSince:     agent.CreatedAt.Format("2006-01-02"),
+		Window:          window,
 	}
 
 	return c.JSON(fiber.Map{
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+const minPasswordLength = 8
+
+type AuthHandler struct {
+	userRepo       *repository.UserRepository
+	credentialRepo *repository.CredentialRepository
+	agentRepo      *repository.AgentRepository
+	tenantRepo     *repository.TenantRepository
+	tokens         *auth.TokenManager
+}
+
+func NewAuthHandler(userRepo *repository.UserRepository, credentialRepo *repository.CredentialRepository, agentRepo *repository.AgentRepository, tenantRepo *repository.TenantRepository, tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		agentRepo:      agentRepo,
+		tenantRepo:     tenantRepo,
+		tokens:         tokens,
+	}
+}
+
+func toUserResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:        user.ID,
+		TenantID:  user.TenantID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Role:      user.Role,
+		IsActive:  user.IsActive,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func toCredentialResponse(credential models.AgentCredential) models.CredentialResponse {
+	return models.CredentialResponse{
+		ID:         credential.ID,
+		AgentID:    credential.AgentID,
+		Name:       credential.Name,
+		KeyPrefix:  credential.KeyPrefix,
+		LastUsedAt: credential.LastUsedAt,
+		RevokedAt:  credential.RevokedAt,
+		CreatedAt:  credential.CreatedAt,
+	}
+}
+
+// canSignIn reports whether the user and their tenant are both active.
+func (h *AuthHandler) canSignIn(user *models.User) (bool, error) {
+	if user == nil || !user.IsActive {
+		return false, nil
+	}
+
+	tenant, err := h.tenantRepo.FindByID(user.TenantID)
+	if err != nil {
+		return false, err
+	}
+
+	return tenant != nil && tenant.IsActive, nil
+}
+
+func (h *AuthHandler) issueTokens(user models.User) (*models.TokenResponse, error) {
+	accessToken, expiresAt, err := h.tokens.IssueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, refreshExpiresAt, err := h.tokens.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.userRepo.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refreshHash,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req models.LoginRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.Email == "" || req.Password == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "email and password are required",
+		})
+	}
+
+	user, err := h.userRepo.FindByEmail(strings.ToLower(req.Email))
+	if err != nil {
+		log.Printf("Failed to fetch user: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+
+	if user == nil || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+
+	allowed, err := h.canSignIn(user)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+
+	if !allowed {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+
+	tokens, err := h.issueTokens(*user)
+	if err != nil {
+		log.Printf("Failed to issue tokens: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+
+	log.Printf("User signed in: %s", user.Email)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens
+// are single use; the presented token is revoked.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "refresh_token is required",
+		})
+	}
+
+	stored, err := h.userRepo.ConsumeRefreshToken(auth.HashToken(req.RefreshToken))
+	if err != nil {
+		log.Printf("Failed to consume refresh token: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	if stored == nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid or expired refresh token",
+		})
+	}
+
+	// The refresh token identifies the user; the tenant comes from the user.
+	user, err := h.userRepo.ForTenant(repository.AllTenants).FindByID(stored.UserID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	allowed, err := h.canSignIn(user)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	if !allowed {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid or expired refresh token",
+		})
+	}
+
+	tokens, err := h.issueTokens(*user)
+	if err != nil {
+		log.Printf("Failed to issue tokens: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "refresh_token is required",
+		})
+	}
+
+	if _, err := h.userRepo.ConsumeRefreshToken(auth.HashToken(req.RefreshToken)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign out",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Signed out successfully",
+	})
+}
+
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	principal := auth.FromContext(c)
+
+	if principal.IsAgent() {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"kind":     principal.Kind,
+				"role":     principal.Role,
+				"agent_id": principal.AgentID,
+			},
+		})
+	}
+
+	user, err := h.userRepo.ForTenant(principal.TenantID).FindByID(principal.UserID)
+	if err != nil || user == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    toUserResponse(*user),
+	})
+}
+
+func (h *AuthHandler) CreateUser(c *fiber.Ctx) error {
+	var req models.UserRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "a valid email is required",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	if len(req.Password) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "password must be at least 8 characters",
+		})
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+
+	if !models.IsValidUserRole(req.Role) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "role must be: admin, dispatcher, or viewer",
+		})
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create user",
+		})
+	}
+
+	user := models.User{
+		Email:        strings.ToLower(req.Email),
+		Name:         req.Name,
+		PasswordHash: hash,
+		Role:         req.Role,
+		IsActive:     true,
+	}
+
+	if err := h.userRepo.ForTenant(auth.TenantID(c)).Create(&user); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create user",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("User created: %s (%s)", user.Email, user.Role)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "User created successfully",
+		"data":    toUserResponse(user),
+	})
+}
+
+// UpdateUserRole changes a user's role. The user's refresh tokens are
+// revoked so the new role applies once their current access token expires.
+func (h *AuthHandler) UpdateUserRole(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "user id must be a positive integer",
+		})
+	}
+
+	var req models.UserRoleUpdate
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if !models.IsValidUserRole(req.Role) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "role must be: admin, dispatcher, or viewer",
+		})
+	}
+
+	if uint(userID) == auth.FromContext(c).UserID && req.Role != models.RoleAdmin {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "You cannot remove your own admin role",
+		})
+	}
+
+	userRepo := h.userRepo.ForTenant(auth.TenantID(c))
+
+	if err := userRepo.UpdateRole(uint(userID), req.Role); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "Failed to update role",
+			"details": err.Error(),
+		})
+	}
+
+	if err := h.userRepo.RevokeRefreshTokens(uint(userID)); err != nil {
+		log.Printf("Failed to revoke refresh tokens for user %d: %v", userID, err)
+	}
+
+	user, err := userRepo.FindByID(uint(userID))
+	if err != nil || user == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch user",
+		})
+	}
+
+	log.Printf("User %s is now %s", user.Email, user.Role)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role updated successfully",
+		"data":    toUserResponse(*user),
+	})
+}
+
+func (h *AuthHandler) CreateAgentCredential(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
+	agentID := c.Params("id")
+
+	var req models.CredentialRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	agent, err := agentRepo.FindByID(agentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	if agent == nil || !agent.IsActive {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create credential",
+		})
+	}
+
+	credential := models.AgentCredential{
+		AgentID:   agentID,
+		Name:      req.Name,
+		KeyPrefix: prefix,
+		KeyHash:   hash,
+	}
+
+	if err := h.credentialRepo.ForTenant(agent.TenantID).Create(&credential); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create credential",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("API key %s issued for agent %s", prefix, agentID)
+
+	response := toCredentialResponse(credential)
+	response.APIKey = key
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Store this API key now, it will not be shown again",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) ListAgentCredentials(c *fiber.Ctx) error {
+	credentials, err := h.credentialRepo.ForTenant(auth.TenantID(c)).FindByAgentID(c.Params("id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch credentials",
+		})
+	}
+
+	responses := make([]models.CredentialResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		responses = append(responses, toCredentialResponse(credential))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+func (h *AuthHandler) RevokeAgentCredential(c *fiber.Ctx) error {
+	credentialID, err := c.ParamsInt("credentialId")
+	if err != nil || credentialID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "credential id must be a positive integer",
+		})
+	}
+
+	if err := h.credentialRepo.ForTenant(auth.TenantID(c)).Revoke(c.Params("id"), uint(credentialID)); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "Failed to revoke credential",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Credential revoked successfully",
+	})
+}
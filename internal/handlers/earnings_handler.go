@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultEarningsRange = 30 * 24 * time.Hour
+	maxEarningsRange     = 366 * 24 * time.Hour
+	maxPeakMultiplier    = 10
+)
+
+type EarningsHandler struct {
+	earningsRepo    *repository.EarningsRepository
+	agentRepo       *repository.AgentRepository
+	earningsService *services.EarningsService
+}
+
+func NewEarningsHandler(earningsRepo *repository.EarningsRepository, agentRepo *repository.AgentRepository, earningsService *services.EarningsService) *EarningsHandler {
+	return &EarningsHandler{
+		earningsRepo:    earningsRepo,
+		agentRepo:       agentRepo,
+		earningsService: earningsService,
+	}
+}
+
+func toPayRuleResponse(rule models.PayRule) models.PayRuleResponse {
+	response := models.PayRuleResponse{
+		ID:              rule.ID,
+		VehicleType:     rule.VehicleType,
+		BasePerDelivery: rule.BasePerDelivery,
+		PerKm:           rule.PerKm,
+		Timezone:        rule.Timezone,
+		PeakWindows:     rule.PeakWindows,
+		DailyTargets:    rule.DailyTargets,
+		IsActive:        rule.IsActive,
+		CreatedAt:       rule.CreatedAt,
+		UpdatedAt:       rule.UpdatedAt,
+	}
+	if response.PeakWindows == nil {
+		response.PeakWindows = []models.PeakWindow{}
+	}
+	if response.DailyTargets == nil {
+		response.DailyTargets = []models.DeliveryTarget{}
+	}
+	return response
+}
+
+func toPayoutResponse(payout models.Payout) models.PayoutResponse {
+	return models.PayoutResponse{
+		ID:          payout.ID,
+		AgentID:     payout.AgentID,
+		PeriodStart: payout.PeriodStart,
+		PeriodEnd:   payout.PeriodEnd,
+		Amount:      payout.Amount,
+		EntryCount:  payout.EntryCount,
+		Status:      payout.Status,
+		CreatedBy:   payout.CreatedBy,
+		PaidAt:      payout.PaidAt,
+		CreatedAt:   payout.CreatedAt,
+	}
+}
+
+// applyPayRuleRequest validates the request and copies it onto rule. It
+// returns a non-empty message when the request is invalid.
+func applyPayRuleRequest(rule *models.PayRule, req models.PayRuleRequest) string {
+	if req.BasePerDelivery < 0 || req.PerKm < 0 {
+		return "base_per_delivery and per_km must not be negative"
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return "timezone must be an IANA time zone such as Asia/Kolkata"
+	}
+
+	for _, window := range req.PeakWindows {
+		start, err1 := services.ParseClock(window.Start)
+		end, err2 := services.ParseClock(window.End)
+		if err1 != nil || err2 != nil || start == end {
+			return "every peak window needs a distinct start and end in HH:MM format"
+		}
+		if window.Multiplier < 1 || window.Multiplier > maxPeakMultiplier {
+			return "peak multipliers must be between 1 and 10"
+		}
+		for _, day := range window.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return "peak window days must be 0 (Sunday) to 6 (Saturday)"
+			}
+		}
+	}
+
+	for _, target := range req.DailyTargets {
+		if target.Deliveries < 1 || target.Bonus <= 0 {
+			return "every daily target needs a positive deliveries count and bonus"
+		}
+	}
+
+	rule.VehicleType = strings.ToLower(strings.TrimSpace(req.VehicleType))
+	rule.BasePerDelivery = req.BasePerDelivery
+	rule.PerKm = req.PerKm
+	rule.Timezone = timezone
+	rule.PeakWindows = req.PeakWindows
+	rule.DailyTargets = req.DailyTargets
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	return ""
+}
+
+func parseIDParam(c *fiber.Ctx, name string) (uint, bool) {
+	id, err := c.ParamsInt(name)
+	if err != nil || id < 1 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *EarningsHandler) CreatePayRule(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	var req models.PayRuleRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	rule := models.PayRule{IsActive: true}
+	if msg := applyPayRuleRequest(&rule, req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := earningsRepo.CreateRule(&rule); err != nil {
+		if errors.Is(err, repository.ErrPayRuleExists) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to create pay rule: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create pay rule",
+			"details": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Pay rule created successfully",
+		"data":    toPayRuleResponse(rule),
+	})
+}
+
+func (h *EarningsHandler) ListPayRules(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	rules, err := earningsRepo.FindRules()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch pay rules",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.PayRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		responses = append(responses, toPayRuleResponse(rule))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
+
+func (h *EarningsHandler) UpdatePayRule(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	ruleID, ok := parseIDParam(c, "id")
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "pay rule id must be a positive integer",
+		})
+	}
+
+	var req models.PayRuleRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	rule, err := earningsRepo.FindRule(ruleID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch pay rule",
+			"details": err.Error(),
+		})
+	}
+
+	if rule == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Pay rule not found",
+		})
+	}
+
+	if msg := applyPayRuleRequest(rule, req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := earningsRepo.SaveRule(rule); err != nil {
+		if errors.Is(err, repository.ErrPayRuleExists) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to update pay rule",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Pay rule updated successfully",
+		"data":    toPayRuleResponse(*rule),
+	})
+}
+
+func (h *EarningsHandler) DeletePayRule(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	ruleID, ok := parseIDParam(c, "id")
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "pay rule id must be a positive integer",
+		})
+	}
+
+	if err := earningsRepo.DeleteRule(ruleID); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to delete pay rule",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Pay rule deleted successfully",
+	})
+}
+
+// findAgent responds with 404 and returns false when the agent is not in
+// the caller's tenant.
+func (h *EarningsHandler) findAgent(c *fiber.Ctx, agentID string) (bool, error) {
+	agent, err := h.agentRepo.ForTenant(auth.TenantID(c)).FindByID(agentID)
+	if err != nil {
+		return false, c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
+	}
+
+	if agent == nil {
+		return false, c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	return true, nil
+}
+
+func (h *EarningsHandler) GetEarnings(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	from, to, msg := parseTimeRange(c, defaultEarningsRange, maxEarningsRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if found, err := h.findAgent(c, agentID); !found {
+		return err
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+	if limit < 1 {
+		limit = 100
+	}
+
+	summary, err := h.earningsService.Summary(auth.TenantID(c), agentID, from, to, limit)
+	if err != nil {
+		log.Printf("Failed to build earnings for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch earnings",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    summary,
+	})
+}
+
+func (h *EarningsHandler) AddAdjustment(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	agentID := c.Params("id")
+	var req models.EarningsAdjustmentRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	switch req.Type {
+	case models.EarningBonus:
+		if req.Amount <= 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "a bonus must have a positive amount",
+			})
+		}
+	case models.EarningAdjustment:
+		if req.Amount == 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "amount must not be zero",
+			})
+		}
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "type must be: bonus or adjustment",
+		})
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "reason is required",
+		})
+	}
+	if tooLong(req.Reason) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("reason must not exceed %d characters", maxTextLength),
+		})
+	}
+
+	if found, err := h.findAgent(c, agentID); !found {
+		return err
+	}
+
+	entry := models.EarningsEntry{
+		AgentID:     agentID,
+		Type:        req.Type,
+		Multiplier:  1,
+		Amount:      req.Amount,
+		Description: req.Reason,
+		EarnedAt:    time.Now(),
+	}
+	if req.EarnedAt != nil {
+		entry.EarnedAt = *req.EarnedAt
+	}
+
+	// Payouts only settle entries earned inside their period, so an entry
+	// backdated into a paid period would never be paid.
+	paidUntil, err := earningsRepo.LastPayoutEnd(agentID)
+	if err != nil {
+		log.Printf("Failed to load payouts for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to add earnings entry",
+			"details": err.Error(),
+		})
+	}
+	if paidUntil != nil && entry.EarnedAt.Before(*paidUntil) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("earned_at must not be before the end of the last payout period (%s)", paidUntil.Format(time.RFC3339)),
+		})
+	}
+	if principal := auth.FromContext(c); principal != nil {
+		entry.CreatedBy = principal.Email
+	}
+
+	if _, err := earningsRepo.CreateEntry(&entry); err != nil {
+		log.Printf("Failed to add earnings %s: %v", req.Type, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to add earnings entry",
+			"details": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Earnings entry added successfully",
+		"data":    services.EntryResponses([]models.EarningsEntry{entry})[0],
+	})
+}
+
+func (h *EarningsHandler) CreatePayout(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+	var req models.PayoutRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.From.IsZero() || req.To.IsZero() || !req.To.After(req.From) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "from and to are required and to must be after from",
+		})
+	}
+
+	if req.To.After(time.Now()) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "a payout period must not end in the future",
+		})
+	}
+
+	if found, err := h.findAgent(c, agentID); !found {
+		return err
+	}
+
+	var createdBy string
+	if principal := auth.FromContext(c); principal != nil {
+		createdBy = principal.Email
+	}
+
+	payout, err := h.earningsService.CreatePayout(auth.TenantID(c), agentID, req.From, req.To, createdBy)
+	if err != nil {
+		if errors.Is(err, repository.ErrNothingToPay) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to create payout for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create payout",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Payout %d created for agent %s: %.2f over %d entries", payout.ID, agentID, payout.Amount, payout.EntryCount)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Payout created successfully",
+		"data":    toPayoutResponse(*payout),
+	})
+}
+
+func (h *EarningsHandler) ListPayouts(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	payouts, err := earningsRepo.FindPayouts(c.Params("id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch payouts",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.PayoutResponse, 0, len(payouts))
+	for _, payout := range payouts {
+		responses = append(responses, toPayoutResponse(payout))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
+
+// GetPayout returns the payout statement with its ledger entries.
+func (h *EarningsHandler) GetPayout(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	payoutID, ok := parseIDParam(c, "payoutId")
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "payout id must be a positive integer",
+		})
+	}
+
+	payout, err := earningsRepo.FindPayout(c.Params("id"), payoutID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch payout",
+			"details": err.Error(),
+		})
+	}
+
+	if payout == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Payout not found",
+		})
+	}
+
+	entries, err := earningsRepo.FindPayoutEntries(payout.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch payout entries",
+			"details": err.Error(),
+		})
+	}
+
+	response := toPayoutResponse(*payout)
+	response.Entries = services.EntryResponses(entries)
+	response.ByType = make(map[string]float64)
+	for _, entry := range entries {
+		response.ByType[entry.Type] += entry.Amount
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    response,
+	})
+}
+
+func (h *EarningsHandler) MarkPayoutPaid(c *fiber.Ctx) error {
+	earningsRepo := h.earningsRepo.ForTenant(auth.TenantID(c))
+
+	payoutID, ok := parseIDParam(c, "payoutId")
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "payout id must be a positive integer",
+		})
+	}
+
+	payout, err := earningsRepo.MarkPaid(c.Params("id"), payoutID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPayoutNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Payout not found",
+			})
+		}
+		if errors.Is(err, repository.ErrPayoutAlreadyPaid) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to mark payout as paid",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Payout marked as paid",
+		"data":    toPayoutResponse(*payout),
+	})
+}
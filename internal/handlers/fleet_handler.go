@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type FleetHandler struct {
+	summaryService *services.FleetSummaryService
+}
+
+func NewFleetHandler(summaryService *services.FleetSummaryService) *FleetHandler {
+	return &FleetHandler{
+		summaryService: summaryService,
+	}
+}
+
+func (h *FleetHandler) GetFleetSummary(c *fiber.Ctx) error {
+	summary, err := h.summaryService.Summary(auth.TenantID(c))
+	if err != nil {
+		log.Printf("Failed to build fleet summary: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to build fleet summary",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    summary,
+	})
+}
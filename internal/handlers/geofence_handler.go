@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxGeofenceRadiusM = 100000
+
+type GeofenceHandler struct {
+	geofenceRepo    *repository.GeofenceRepository
+	geofenceService *services.GeofenceService
+}
+
+func NewGeofenceHandler(geofenceRepo *repository.GeofenceRepository, geofenceService *services.GeofenceService) *GeofenceHandler {
+	return &GeofenceHandler{
+		geofenceRepo:    geofenceRepo,
+		geofenceService: geofenceService,
+	}
+}
+
+func toGeofenceResponse(geofence models.Geofence) models.GeofenceResponse {
+	return models.GeofenceResponse{
+		ID:           geofence.ID,
+		Name:         geofence.Name,
+		Category:     geofence.Category,
+		Shape:        geofence.Shape,
+		CenterLat:    geofence.CenterLat,
+		CenterLng:    geofence.CenterLng,
+		RadiusM:      geofence.RadiusM,
+		Vertices:     geofence.Vertices,
+		DwellSeconds: geofence.DwellSeconds,
+		IsActive:     geofence.IsActive,
+		CreatedAt:    geofence.CreatedAt,
+		UpdatedAt:    geofence.UpdatedAt,
+	}
+}
+
+func toGeofenceEventResponses(events []models.GeofenceEvent) []models.GeofenceEventResponse {
+	responses := make([]models.GeofenceEventResponse, 0, len(events))
+	for _, event := range events {
+		responses = append(responses, models.GeofenceEventResponse{
+			ID:         event.ID,
+			GeofenceID: event.GeofenceID,
+			AgentID:    event.AgentID,
+			EventType:  event.EventType,
+			Latitude:   event.Latitude,
+			Longitude:  event.Longitude,
+			DwellSecs:  event.DwellSecs,
+			Timestamp:  event.Timestamp,
+		})
+	}
+	return responses
+}
+
+// applyGeofenceRequest validates the request and copies it onto geofence.
+// It returns a non-empty message when the request is invalid.
+func applyGeofenceRequest(geofence *models.Geofence, req models.GeofenceRequest) string {
+	if req.Name == "" {
+		return "name is required"
+	}
+
+	if req.DwellSeconds < 0 {
+		return "dwell_seconds must not be negative"
+	}
+
+	switch req.Shape {
+	case models.GeofenceCircle:
+		if !validCoordinates(req.CenterLat, req.CenterLng) {
+			return "valid center_lat and center_lng are required for a circle"
+		}
+		if req.RadiusM <= 0 || req.RadiusM > maxGeofenceRadiusM {
+			return "radius_m must be between 0 and 100000 for a circle"
+		}
+		geofence.CenterLat = req.CenterLat
+		geofence.CenterLng = req.CenterLng
+		geofence.RadiusM = req.RadiusM
+		geofence.Vertices = nil
+
+	case models.GeofencePolygon:
+		if len(req.Vertices) < 3 {
+			return "a polygon needs at least 3 vertices"
+		}
+		for _, vertex := range req.Vertices {
+			if !validCoordinates(vertex[0], vertex[1]) {
+				return "every polygon vertex must be a valid [lat, lng] pair"
+			}
+		}
+		geofence.CenterLat = 0
+		geofence.CenterLng = 0
+		geofence.RadiusM = 0
+		geofence.Vertices = models.Polygon(req.Vertices)
+
+	default:
+		return "shape must be: circle or polygon"
+	}
+
+	geofence.Name = req.Name
+	geofence.Category = req.Category
+	geofence.Shape = req.Shape
+	geofence.DwellSeconds = req.DwellSeconds
+	if req.IsActive != nil {
+		geofence.IsActive = *req.IsActive
+	}
+
+	return ""
+}
+
+func parseGeofenceID(c *fiber.Ctx) (uint, bool) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id < 1 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *GeofenceHandler) CreateGeofence(c *fiber.Ctx) error {
+	geofenceRepo := h.geofenceRepo.ForTenant(auth.TenantID(c))
+
+	var req models.GeofenceRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	geofence := models.Geofence{IsActive: true}
+	if msg := applyGeofenceRequest(&geofence, req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := geofenceRepo.Create(&geofence); err != nil {
+		log.Printf("Failed to create geofence: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create geofence",
+			"details": err.Error(),
+		})
+	}
+
+	h.geofenceService.Invalidate()
+
+	log.Printf("Geofence created: ID=%d, Name=%s, Shape=%s", geofence.ID, geofence.Name, geofence.Shape)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Geofence created successfully",
+		"data":    toGeofenceResponse(geofence),
+	})
+}
+
+func (h *GeofenceHandler) ListGeofences(c *fiber.Ctx) error {
+	geofenceRepo := h.geofenceRepo.ForTenant(auth.TenantID(c))
+
+	geofences, err := geofenceRepo.FindAll(c.QueryBool("active", false))
+	if err != nil {
+		log.Printf("Failed to fetch geofences: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch geofences",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.GeofenceResponse, 0, len(geofences))
+	for _, geofence := range geofences {
+		responses = append(responses, toGeofenceResponse(geofence))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
+
+func (h *GeofenceHandler) GetGeofence(c *fiber.Ctx) error {
+	geofenceRepo := h.geofenceRepo.ForTenant(auth.TenantID(c))
+
+	geofenceID, ok := parseGeofenceID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "geofence id must be a positive integer",
+		})
+	}
+
+	geofence, err := geofenceRepo.FindByID(geofenceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch geofence",
+			"details": err.Error(),
+		})
+	}
+
+	if geofence == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Geofence not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    toGeofenceResponse(*geofence),
+	})
+}
+
+func (h *GeofenceHandler) UpdateGeofence(c *fiber.Ctx) error {
+	geofenceRepo := h.geofenceRepo.ForTenant(auth.TenantID(c))
+
+	geofenceID, ok := parseGeofenceID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "geofence id must be a positive integer",
+		})
+	}
+
+	var req models.GeofenceRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	geofence, err := geofenceRepo.FindByID(geofenceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch geofence",
+			"details": err.Error(),
+		})
+	}
+
+	if geofence == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Geofence not found",
+		})
+	}
+
+	if msg := applyGeofenceRequest(geofence, req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := geofenceRepo.Save(geofence); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to update geofence",
+			"details": err.Error(),
+		})
+	}
+
+	h.geofenceService.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Geofence updated successfully",
+		"data":    toGeofenceResponse(*geofence),
+	})
+}
+
+func (h *GeofenceHandler) DeleteGeofence(c *fiber.Ctx) error {
+	geofenceRepo := h.geofenceRepo.ForTenant(auth.TenantID(c))
+
+	geofenceID, ok := parseGeofenceID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "geofence id must be a positive integer",
+		})
+	}
+
+	if err := geofenceRepo.Delete(geofenceID); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to delete geofence",
+			"details": err.Error(),
+		})
+	}
+
+	h.geofenceService.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Geofence deleted successfully",
+	})
+}
+
+func (h *GeofenceHandler) GetGeofenceEvents(c *fiber.Ctx) error {
+	geofenceID, ok := parseGeofenceID(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "geofence id must be a positive integer",
+		})
+	}
+
+	return h.listEvents(c, repository.GeofenceEventFilter{
+		GeofenceID: geofenceID,
+		AgentID:    c.Query("agent_id"),
+	})
+}
+
+func (h *GeofenceHandler) GetAgentGeofenceEvents(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	if agentID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "agent_id parameter is required",
+		})
+	}
+
+	return h.listEvents(c, repository.GeofenceEventFilter{
+		AgentID:    agentID,
+		GeofenceID: uint(c.QueryInt("geofence_id", 0)),
+	})
+}
+
+func (h *GeofenceHandler) listEvents(c *fiber.Ctx, filter repository.GeofenceEventFilter) error {
+	filter.EventType = c.Query("type")
+	if filter.EventType != "" && filter.EventType != models.GeofenceEnter &&
+		filter.EventType != models.GeofenceExit && filter.EventType != models.GeofenceDwell {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "type must be: enter, exit, or dwell",
+		})
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z",
+			})
+		}
+		filter.From = t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z",
+			})
+		}
+		filter.To = t
+	}
+
+	filter.Limit = c.QueryInt("limit", 100)
+	if filter.Limit > 1000 {
+		filter.Limit = 1000
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 100
+	}
+
+	events, err := h.geofenceRepo.ForTenant(auth.TenantID(c)).FindEvents(filter)
+	if err != nil {
+		log.Printf("Failed to fetch geofence events: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch geofence events",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(events),
+		"data":    toGeofenceEventResponses(events),
+	})
+}
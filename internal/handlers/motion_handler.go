@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MotionHandler struct {
+	motionRepo *repository.MotionRepository
+	agentRepo  *repository.AgentRepository
+}
+
+func NewMotionHandler(motionRepo *repository.MotionRepository, agentRepo *repository.AgentRepository) *MotionHandler {
+	return &MotionHandler{
+		motionRepo: motionRepo,
+		agentRepo:  agentRepo,
+	}
+}
+
+// GetAgentMotion returns the agent's smoothed motion state and its recent
+// state changes, newest first, optionally limited to from/to.
+func (h *MotionHandler) GetAgentMotion(c *fiber.Ctx) error {
+	motionRepo := h.motionRepo.ForTenant(auth.TenantID(c))
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
+	agentID := c.Params("id")
+
+	var from, to time.Time
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if value := c.Query(param.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z",
+				})
+			}
+			*param.target = t
+		}
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+	if limit < 1 {
+		limit = 100
+	}
+
+	agent, err := agentRepo.FindByID(agentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
+	}
+
+	if agent == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	state, err := motionRepo.FindState(agentID)
+	if err != nil {
+		log.Printf("Failed to fetch motion state for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch motion state",
+			"details": err.Error(),
+		})
+	}
+
+	events, err := motionRepo.FindEvents(agentID, from, to, limit)
+	if err != nil {
+		log.Printf("Failed to fetch motion events for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch motion events",
+			"details": err.Error(),
+		})
+	}
+
+	response := models.MotionStateResponse{
+		AgentID: agentID,
+		State:   models.MotionUnknown,
+	}
+	if state != nil {
+		response = models.MotionStateResponse{
+			AgentID:        state.AgentID,
+			State:          state.State,
+			Since:          state.Since,
+			Candidate:      state.Candidate,
+			CandidateSince: state.CandidateSince,
+			SpeedKmh:       state.SpeedKmh,
+			LastSeenAt:     state.LastSeenAt,
+		}
+	}
+
+	eventResponses := make([]models.MotionEventResponse, 0, len(events))
+	for _, event := range events {
+		eventResponses = append(eventResponses, services.MotionEventResponse(event))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"state":  response,
+			"events": eventResponses,
+		},
+	})
+}
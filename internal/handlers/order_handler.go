@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrderHandler struct {
+	orderRepo       *repository.OrderRepository
+	agentRepo       *repository.AgentRepository
+	dispatchService *services.DispatchService
+	earningsService *services.EarningsService
+}
+
+func NewOrderHandler(orderRepo *repository.OrderRepository, agentRepo *repository.AgentRepository, dispatchService *services.DispatchService, earningsService *services.EarningsService) *OrderHandler {
+	return &OrderHandler{
+		orderRepo:       orderRepo,
+		agentRepo:       agentRepo,
+		dispatchService: dispatchService,
+		earningsService: earningsService,
+	}
+}
+
+func toOrderResponse(order models.Order) models.OrderResponse {
+	return models.OrderResponse{
+		ID:              order.ID,
+		AgentID:         order.AgentIDValue(),
+		CustomerName:    order.CustomerName,
+		CustomerPhone:   order.CustomerPhone,
+		PickupLatitude:  order.PickupLatitude,
+		PickupLongitude: order.PickupLongitude,
+		PickupAddress:   order.PickupAddress,
+		DropLatitude:    order.DropLatitude,
+		DropLongitude:   order.DropLongitude,
+		DropAddress:     order.DropAddress,
+		Notes:           order.Notes,
+		Status:          order.Status,
+		FailureReason:   order.FailureReason,
+		AssignedAt:      order.AssignedAt,
+		PickedUpAt:      order.PickedUpAt,
+		InTransitAt:     order.InTransitAt,
+		DeliveredAt:     order.DeliveredAt,
+		FailedAt:        order.FailedAt,
+		CancelledAt:     order.CancelledAt,
+		CreatedAt:       order.CreatedAt,
+		UpdatedAt:       order.UpdatedAt,
+	}
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 && !(lat == 0 && lng == 0)
+}
+
+func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	var req models.OrderRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.CustomerName == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "customer_name is required",
+		})
+	}
+
+	if req.CustomerPhone == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "customer_phone is required",
+		})
+	}
+
+	if !validCoordinates(req.PickupLatitude, req.PickupLongitude) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "valid pickup_latitude and pickup_longitude are required",
+		})
+	}
+
+	if !validCoordinates(req.DropLatitude, req.DropLongitude) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "valid drop_latitude and drop_longitude are required",
+		})
+	}
+
+	order := models.Order{
+		CustomerName:    req.CustomerName,
+		CustomerPhone:   req.CustomerPhone,
+		PickupLatitude:  req.PickupLatitude,
+		PickupLongitude: req.PickupLongitude,
+		PickupAddress:   req.PickupAddress,
+		DropLatitude:    req.DropLatitude,
+		DropLongitude:   req.DropLongitude,
+		DropAddress:     req.DropAddress,
+		Notes:           req.Notes,
+	}
+
+	if err := orderRepo.Create(&order); err != nil {
+		log.Printf("Failed to create order: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create order",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Order created: ID=%d, Customer=%s", order.ID, order.CustomerName)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Order created successfully",
+		"data":    toOrderResponse(order),
+	})
+}
+
+func (h *OrderHandler) GetOrder(c *fiber.Ctx) error {
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "order id must be a positive integer",
+		})
+	}
+
+	order, err := orderRepo.FindByID(uint(orderID))
+	if err != nil {
+		log.Printf("Failed to fetch order: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch order",
+			"details": err.Error(),
+		})
+	}
+
+	if order == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    toOrderResponse(*order),
+	})
+}
+
+func (h *OrderHandler) ListOrders(c *fiber.Ctx) error {
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	status := c.Query("status", "")
+	agentID := c.Query("agent_id", "")
+
+	if limit > 100 {
+		limit = 100
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	if status != "" && !models.IsValidOrderStatus(status) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be: created, assigned, picked_up, in_transit, delivered, failed, or cancelled",
+		})
+	}
+
+	offset := (page - 1) * limit
+
+	orders, totalCount, err := orderRepo.FindAll(limit, offset, status, agentID)
+	if err != nil {
+		log.Printf("Failed to fetch orders: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch orders",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.OrderResponse, 0, len(orders))
+	for _, order := range orders {
+		responses = append(responses, toOrderResponse(order))
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit != 0 {
+		totalPages++
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+		"pagination": fiber.Map{
+			"total":        totalCount,
+			"page":         page,
+			"limit":        limit,
+			"total_pages":  totalPages,
+			"has_next":     page < totalPages,
+			"has_previous": page > 1,
+		},
+	})
+}
+
+func (h *OrderHandler) UpdateOrderStatus(c *fiber.Ctx) error {
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "order id must be a positive integer",
+		})
+	}
+
+	var req models.OrderStatusUpdate
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if !models.IsValidOrderStatus(req.Status) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be: created, assigned, picked_up, in_transit, delivered, failed, or cancelled",
+		})
+	}
+
+	if req.Status == models.OrderAssigned {
+		if req.AgentID == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "agent_id is required when assigning an order",
+			})
+		}
+
+		agent, err := agentRepo.FindByID(req.AgentID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error":   "Failed to fetch agent",
+				"details": err.Error(),
+			})
+		}
+
+		if agent == nil || !agent.IsActive {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "agent_id must reference an active agent",
+			})
+		}
+	}
+
+	order, err := orderRepo.Transition(uint(orderID), req.Status, req.AgentID, req.Reason, h.earningsService.RecordDelivery)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Order not found",
+			})
+		}
+		if errors.Is(err, repository.ErrInvalidOrderTransition) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":   "Illegal order status transition",
+				"details": err.Error(),
+			})
+		}
+		if errors.Is(err, repository.ErrAgentUnavailable) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Agent must be available or busy to take the order",
+			})
+		}
+		log.Printf("Failed to update order status: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to update order status",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Order %d moved to %s", order.ID, order.Status)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Order status updated successfully",
+		"data":    toOrderResponse(*order),
+	})
+}
+
+func (h *OrderHandler) DispatchOrder(c *fiber.Ctx) error {
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "order id must be a positive integer",
+		})
+	}
+
+	var req models.DispatchRequest
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	validVehicles := map[string]bool{
+		"bike": true, "scooter": true, "car": true, "truck": true,
+	}
+
+	vehicleTypes := make([]string, 0, len(req.VehicleTypes))
+	for _, vehicleType := range req.VehicleTypes {
+		vehicleType = strings.ToLower(vehicleType)
+		if !validVehicles[vehicleType] {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "vehicle_types must contain only: bike, scooter, car, or truck",
+			})
+		}
+		vehicleTypes = append(vehicleTypes, vehicleType)
+	}
+
+	if req.MaxRadiusM < 0 || req.MaxFixAgeSeconds < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "max_radius_m and max_fix_age_seconds must not be negative",
+		})
+	}
+
+	result, err := h.dispatchService.Dispatch(auth.TenantID(c), uint(orderID), services.DispatchOptions{
+		VehicleTypes: vehicleTypes,
+		MaxRadiusM:   req.MaxRadiusM,
+		MaxFixAge:    time.Duration(req.MaxFixAgeSeconds) * time.Second,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrOrderNotFound):
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Order not found",
+			})
+		case errors.Is(err, repository.ErrInvalidOrderTransition):
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Order cannot be dispatched in its current status",
+			})
+		case errors.Is(err, services.ErrNoAgentAvailable):
+			return c.Status(409).JSON(fiber.Map{
+				"error": "No available agent found for this order",
+			})
+		}
+		log.Printf("Failed to dispatch order: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to dispatch order",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Order %d dispatched to agent %s (%.0f m away, %d candidates)",
+		result.Order.ID, result.Agent.ID, result.DistanceM, result.Evaluated)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Order dispatched successfully",
+		"data": fiber.Map{
+			"order": toOrderResponse(result.Order),
+			"agent": models.AgentResponse{
+				ID:          result.Agent.ID,
+				Name:        result.Agent.Name,
+				Phone:       result.Agent.Phone,
+				Email:       result.Agent.Email,
+				VehicleType: result.Agent.VehicleType,
+				Status:      result.Agent.Status,
+				IsActive:    result.Agent.IsActive,
+				CreatedAt:   result.Agent.CreatedAt,
+				UpdatedAt:   result.Agent.UpdatedAt,
+			},
+			"distance_m":           result.DistanceM,
+			"candidates_evaluated": result.Evaluated,
+		},
+	})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultPlaybackRange = 24 * time.Hour
+	maxPlaybackRange     = 2 * 24 * time.Hour
+	defaultPlaybackGap   = 5 * time.Minute
+	maxPlaybackTolerance = 1000
+	maxExportRange       = 31 * 24 * time.Hour
+)
+
+type PlaybackHandler struct {
+	playbackService *services.PlaybackService
+	exportService   *services.TrackExportService
+}
+
+func NewPlaybackHandler(playbackService *services.PlaybackService, exportService *services.TrackExportService) *PlaybackHandler {
+	return &PlaybackHandler{
+		playbackService: playbackService,
+		exportService:   exportService,
+	}
+}
+
+// parsePlaybackOptions reads tolerance_m, bucket_s, gap_s and
+// include_suspect. It returns a non-empty message when they are invalid.
+func parsePlaybackOptions(c *fiber.Ctx) (services.PlaybackOptions, string) {
+	opts := services.PlaybackOptions{
+		ToleranceM:     c.QueryFloat("tolerance_m", 0),
+		Bucket:         time.Duration(c.QueryInt("bucket_s", 0)) * time.Second,
+		Gap:            time.Duration(c.QueryInt("gap_s", int(defaultPlaybackGap.Seconds()))) * time.Second,
+		IncludeSuspect: c.QueryBool("include_suspect", false),
+	}
+
+	if opts.ToleranceM < 0 || opts.ToleranceM > maxPlaybackTolerance {
+		return opts, "tolerance_m must be between 0 and 1000"
+	}
+
+	if opts.Bucket < 0 || opts.Bucket > time.Hour {
+		return opts, "bucket_s must be between 0 and 3600"
+	}
+
+	if opts.Gap < 0 {
+		return opts, "gap_s must not be negative"
+	}
+
+	return opts, ""
+}
+
+// GetPlayback returns the agent's route over from/to (default: the last
+// 24 hours) for replaying on a map: oldest first, optionally downsampled
+// to one fix per bucket_s and simplified to tolerance_m, and split into
+// segments wherever the agent was silent for longer than gap_s.
+func (h *PlaybackHandler) GetPlayback(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	from, to, msg := parseTimeRange(c, defaultPlaybackRange, maxPlaybackRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	opts, msg := parsePlaybackOptions(c)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	playback, err := h.playbackService.Playback(auth.TenantID(c), agentID, from, to, opts)
+	if err != nil {
+		log.Printf("Failed to build playback for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to build playback",
+			"details": err.Error(),
+		})
+	}
+
+	if playback == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	log.Printf("Built playback for agent %s: %d of %d points in %d segments",
+		agentID, playback.ReturnedPoints, playback.RawPoints, len(playback.Segments))
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    playback,
+	})
+}
+
+// ExportTrack streams the agent's history over from/to (default: the last
+// 24 hours) as a GeoJSON, GPX or KML file, chosen by the format query.
+func (h *PlaybackHandler) ExportTrack(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	format, ok := services.ParseTrackFormat(c.Query("format", string(services.TrackGeoJSON)))
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be: geojson, gpx, or kml",
+		})
+	}
+
+	from, to, msg := parseTimeRange(c, defaultPlaybackRange, maxExportRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	export, err := h.exportService.Open(auth.TenantID(c), agentID, from, to)
+	if err != nil {
+		log.Printf("Failed to export track for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to export track",
+			"details": err.Error(),
+		})
+	}
+
+	if export == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	if !export.HasPoints() {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "No location history found for this agent",
+		})
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s.%s", agentID,
+		from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"), format.Extension())
+
+	c.Set("Content-Type", format.ContentType())
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := export.WriteTo(w, format)
+		if err != nil {
+			log.Printf("Track export for agent %s stopped after %d points: %v", agentID, count, err)
+			return
+		}
+		log.Printf("Exported %d points for agent %s as %s", count, agentID, format)
+	})
+
+	return nil
+}
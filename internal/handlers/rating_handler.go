@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxRatingComment  = 1000
+	maxRatingTags     = 10
+	maxRatingTagLen   = 32
+	defaultRecentDays = 30
+)
+
+type RatingHandler struct {
+	ratingRepo *repository.RatingRepository
+	orderRepo  *repository.OrderRepository
+	agentRepo  *repository.AgentRepository
+}
+
+func NewRatingHandler(ratingRepo *repository.RatingRepository, orderRepo *repository.OrderRepository, agentRepo *repository.AgentRepository) *RatingHandler {
+	return &RatingHandler{
+		ratingRepo: ratingRepo,
+		orderRepo:  orderRepo,
+		agentRepo:  agentRepo,
+	}
+}
+
+func toRatingResponse(rating models.Rating) models.RatingResponse {
+	tags := []string(rating.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return models.RatingResponse{
+		ID:          rating.ID,
+		OrderID:     rating.OrderID,
+		AgentID:     rating.AgentID,
+		Score:       rating.Score,
+		Comment:     rating.Comment,
+		Tags:        tags,
+		SubmittedBy: rating.SubmittedBy,
+		CreatedAt:   rating.CreatedAt,
+	}
+}
+
+// normalizeTags lowercases, trims and de-duplicates the tags. It returns a
+// non-empty message when they are invalid.
+func normalizeTags(tags []string) ([]string, string) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxRatingTagLen {
+			return nil, "tags must be at most 32 characters"
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxRatingTags {
+		return nil, "at most 10 tags are allowed"
+	}
+
+	return normalized, ""
+}
+
+func roundRating(average float64) float64 {
+	return math.Round(average*100) / 100
+}
+
+func summarizeRatings(ratingRepo *repository.RatingRepository, agentID string, recentDays int) (*models.RatingSummary, error) {
+	average, count, err := ratingRepo.Average(agentID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	recentAverage, recentCount, err := ratingRepo.Average(agentID, time.Now().AddDate(0, 0, -recentDays), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	distribution, err := ratingRepo.Distribution(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := ratingRepo.TagCounts(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RatingSummary{
+		AgentID:       agentID,
+		Average:       roundRating(average),
+		Count:         count,
+		RecentAverage: roundRating(recentAverage),
+		RecentCount:   recentCount,
+		RecentDays:    recentDays,
+		Distribution:  distribution,
+		Tags:          tags,
+	}, nil
+}
+
+func (h *RatingHandler) SubmitRating(c *fiber.Ctx) error {
+	ratingRepo := h.ratingRepo.ForTenant(auth.TenantID(c))
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "order id must be a positive integer",
+		})
+	}
+
+	var req models.RatingRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.Score < models.MinRatingScore || req.Score > models.MaxRatingScore {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "score must be between 1 and 5",
+		})
+	}
+
+	comment := strings.TrimSpace(req.Comment)
+	if len(comment) > maxRatingComment {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "comment must be at most 1000 characters",
+		})
+	}
+
+	tags, msg := normalizeTags(req.Tags)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	order, err := orderRepo.FindByID(uint(orderID))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch order",
+			"details": err.Error(),
+		})
+	}
+
+	if order == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	if order.Status != models.OrderDelivered || order.AgentIDValue() == "" {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "only delivered orders can be rated",
+		})
+	}
+
+	rating := models.Rating{
+		OrderID: order.ID,
+		AgentID: order.AgentIDValue(),
+		Score:   req.Score,
+		Comment: comment,
+		Tags:    models.StringList(tags),
+	}
+	if principal := auth.FromContext(c); principal != nil {
+		rating.SubmittedBy = principal.Email
+	}
+
+	if err := ratingRepo.Create(&rating); err != nil {
+		if errors.Is(err, repository.ErrAlreadyRated) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to save rating for order %d: %v", order.ID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to save rating",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Order %d rated %d for agent %s", order.ID, rating.Score, rating.AgentID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Rating submitted successfully",
+		"data":    toRatingResponse(rating),
+	})
+}
+
+func (h *RatingHandler) GetOrderRating(c *fiber.Ctx) error {
+	ratingRepo := h.ratingRepo.ForTenant(auth.TenantID(c))
+	orderRepo := h.orderRepo.ForTenant(auth.TenantID(c))
+
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "order id must be a positive integer",
+		})
+	}
+
+	order, err := orderRepo.FindByID(uint(orderID))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch order",
+			"details": err.Error(),
+		})
+	}
+
+	// Agents may only see ratings of their own orders; others are reported
+	// as missing.
+	principal := auth.FromContext(c)
+	if order == nil || principal == nil || !principal.Can(auth.PermRatingsRead, order.AgentIDValue()) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	rating, err := ratingRepo.FindByOrder(uint(orderID))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch rating",
+			"details": err.Error(),
+		})
+	}
+
+	if rating == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Order has not been rated",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    toRatingResponse(*rating),
+	})
+}
+
+// ListAgentRatings returns the agent's ratings, newest first, with lifetime
+// and rolling averages over the last recent_days days.
+func (h *RatingHandler) ListAgentRatings(c *fiber.Ctx) error {
+	ratingRepo := h.ratingRepo.ForTenant(auth.TenantID(c))
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
+	agentID := c.Params("id")
+
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+
+	if limit > 100 {
+		limit = 100
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	offset := (page - 1) * limit
+
+	recentDays := c.QueryInt("recent_days", defaultRecentDays)
+	if recentDays < 1 || recentDays > 365 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "recent_days must be between 1 and 365",
+		})
+	}
+
+	agent, err := agentRepo.FindByID(agentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
+	}
+
+	if agent == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	summary, err := summarizeRatings(ratingRepo, agentID, recentDays)
+	if err != nil {
+		log.Printf("Failed to summarize ratings for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to summarize ratings",
+			"details": err.Error(),
+		})
+	}
+
+	ratings, totalCount, err := ratingRepo.FindByAgent(agentID, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch ratings",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.RatingResponse, 0, len(ratings))
+	for _, rating := range ratings {
+		responses = append(responses, toRatingResponse(rating))
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit != 0 {
+		totalPages++
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"summary": summary,
+		"data":    responses,
+		"pagination": fiber.Map{
+			"total":        totalCount,
+			"page":         page,
+			"limit":        limit,
+			"total_pages":  totalPages,
+			"has_next":     page < totalPages,
+			"has_previous": page > 1,
+		},
+	})
+}
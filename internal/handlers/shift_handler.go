@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultShiftRange = 7 * 24 * time.Hour
+	maxShiftRange     = 62 * 24 * time.Hour
+)
+
+type ShiftHandler struct {
+	shiftRepo    *repository.ShiftRepository
+	agentRepo    *repository.AgentRepository
+	shiftService *services.ShiftService
+	hub          *realtime.Hub
+}
+
+func NewShiftHandler(shiftRepo *repository.ShiftRepository, agentRepo *repository.AgentRepository, shiftService *services.ShiftService, hub *realtime.Hub) *ShiftHandler {
+	return &ShiftHandler{
+		shiftRepo:    shiftRepo,
+		agentRepo:    agentRepo,
+		shiftService: shiftService,
+		hub:          hub,
+	}
+}
+
+func (h *ShiftHandler) toShiftResponse(shift models.Shift, now time.Time) models.ShiftResponse {
+	return models.ShiftResponse{
+		ID:             shift.ID,
+		AgentID:        shift.AgentID,
+		StartsAt:       shift.StartsAt,
+		EndsAt:         shift.EndsAt,
+		Status:         h.shiftService.DisplayStatus(shift, now),
+		Notes:          shift.Notes,
+		ClockInAt:      shift.ClockInAt,
+		ClockOutAt:     shift.ClockOutAt,
+		Late:           h.shiftService.IsLate(shift, now),
+		LateSecs:       shift.LateSecs,
+		LeftEarly:      h.shiftService.LeftEarly(shift),
+		EarlyLeaveSecs: shift.EarlyLeaveSecs,
+		MissedClockOut: shift.MissedClockOut,
+		CreatedBy:      shift.CreatedBy,
+		CreatedAt:      shift.CreatedAt,
+	}
+}
+
+func (h *ShiftHandler) CreateShift(c *fiber.Ctx) error {
+	shiftRepo := h.shiftRepo.ForTenant(auth.TenantID(c))
+	agentRepo := h.agentRepo.ForTenant(auth.TenantID(c))
+
+	var req models.ShiftRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if req.AgentID == "" || req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "agent_id, starts_at and ends_at are required",
+		})
+	}
+
+	if !req.EndsAt.After(req.StartsAt) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "ends_at must be after starts_at",
+		})
+	}
+
+	if maxLength := h.shiftService.Config().MaxShiftLength; req.EndsAt.Sub(req.StartsAt) > maxLength {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "shift must not be longer than " + maxLength.String(),
+		})
+	}
+
+	if !req.EndsAt.After(time.Now()) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "cannot schedule a shift that has already ended",
+		})
+	}
+
+	if tooLong(req.Notes) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("notes must not exceed %d characters", maxTextLength),
+		})
+	}
+
+	agent, err := agentRepo.FindByID(req.AgentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
+	}
+
+	if agent == nil || !agent.IsActive {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	shift := models.Shift{
+		AgentID:  req.AgentID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Notes:    req.Notes,
+	}
+	if principal := auth.FromContext(c); principal != nil {
+		shift.CreatedBy = principal.Email
+	}
+
+	if err := shiftRepo.Create(&shift); err != nil {
+		if errors.Is(err, repository.ErrShiftOverlap) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Failed to create shift: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create shift",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Shift scheduled: ID=%d, Agent=%s, %s - %s", shift.ID, shift.AgentID,
+		shift.StartsAt.Format(time.RFC3339), shift.EndsAt.Format(time.RFC3339))
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Shift scheduled successfully",
+		"data":    h.toShiftResponse(shift, time.Now()),
+	})
+}
+
+// ListShifts lists shifts overlapping the from/to range. The flag query
+// parameter narrows it to late, early_leave, missed or missed_clock_out
+// shifts.
+func (h *ShiftHandler) ListShifts(c *fiber.Ctx) error {
+	return h.listShifts(c, c.Query("agent_id"))
+}
+
+func (h *ShiftHandler) ListAgentShifts(c *fiber.Ctx) error {
+	return h.listShifts(c, c.Params("id"))
+}
+
+func (h *ShiftHandler) listShifts(c *fiber.Ctx, agentID string) error {
+	shiftRepo := h.shiftRepo.ForTenant(auth.TenantID(c))
+
+	from, to, msg := parseTimeRange(c, defaultShiftRange, maxShiftRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	flag := c.Query("flag")
+	switch flag {
+	case "", "late", "early_leave", "missed", "missed_clock_out":
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "flag must be: late, early_leave, missed or missed_clock_out",
+		})
+	}
+
+	shifts, err := shiftRepo.Find(repository.ShiftFilter{
+		AgentID: agentID,
+		Status:  c.Query("status"),
+		From:    from,
+		To:      to,
+	})
+	if err != nil {
+		log.Printf("Failed to fetch shifts: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch shifts",
+			"details": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	responses := make([]models.ShiftResponse, 0, len(shifts))
+	for _, shift := range shifts {
+		response := h.toShiftResponse(shift, now)
+
+		switch {
+		case flag == "late" && !response.Late,
+			flag == "early_leave" && !response.LeftEarly,
+			flag == "missed" && response.Status != models.ShiftMissed,
+			flag == "missed_clock_out" && !response.MissedClockOut:
+			continue
+		}
+
+		responses = append(responses, response)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
+
+func (h *ShiftHandler) GetShift(c *fiber.Ctx) error {
+	shiftRepo := h.shiftRepo.ForTenant(auth.TenantID(c))
+
+	shiftID, err := c.ParamsInt("id")
+	if err != nil || shiftID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "shift id must be a positive integer",
+		})
+	}
+
+	shift, err := shiftRepo.FindByID(uint(shiftID))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch shift",
+			"details": err.Error(),
+		})
+	}
+
+	// Agents may only see their own shifts; others are reported as missing.
+	principal := auth.FromContext(c)
+	if shift == nil || principal == nil || !principal.Can(auth.PermShiftsRead, shift.AgentID) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Shift not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    h.toShiftResponse(*shift, time.Now()),
+	})
+}
+
+func (h *ShiftHandler) CancelShift(c *fiber.Ctx) error {
+	shiftRepo := h.shiftRepo.ForTenant(auth.TenantID(c))
+
+	shiftID, err := c.ParamsInt("id")
+	if err != nil || shiftID < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "shift id must be a positive integer",
+		})
+	}
+
+	shift, err := shiftRepo.Cancel(uint(shiftID))
+	if err != nil {
+		if errors.Is(err, repository.ErrShiftNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Shift not found",
+			})
+		}
+		if errors.Is(err, repository.ErrShiftNotScheduled) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to cancel shift",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Shift cancelled successfully",
+		"data":    h.toShiftResponse(*shift, time.Now()),
+	})
+}
+
+func (h *ShiftHandler) ClockIn(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	shift, previous, err := h.shiftService.ClockIn(auth.TenantID(c), agentID, statusTrigger(c, "clocked in"))
+	if err != nil {
+		return h.clockError(c, "Failed to clock in", err)
+	}
+
+	if previous == "offline" {
+		h.publishStatus(c, agentID, previous, "available")
+	}
+
+	response := h.toShiftResponse(*shift, time.Now())
+	if response.Late {
+		log.Printf("Agent %s clocked in %ds late for shift %d", agentID, shift.LateSecs, shift.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Clocked in successfully",
+		"data":    response,
+	})
+}
+
+func (h *ShiftHandler) ClockOut(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	shift, previous, err := h.shiftService.ClockOut(auth.TenantID(c), agentID, statusTrigger(c, "clocked out"))
+	if err != nil {
+		return h.clockError(c, "Failed to clock out", err)
+	}
+
+	if previous != "offline" {
+		h.publishStatus(c, agentID, previous, "offline")
+	}
+
+	response := h.toShiftResponse(*shift, time.Now())
+	if response.LeftEarly {
+		log.Printf("Agent %s clocked out %ds early from shift %d", agentID, shift.EarlyLeaveSecs, shift.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Clocked out successfully",
+		"data":    response,
+	})
+}
+
+func (h *ShiftHandler) clockError(c *fiber.Ctx, message string, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNoShiftToClockIn),
+		errors.Is(err, repository.ErrAlreadyClockedIn),
+		errors.Is(err, repository.ErrNotClockedIn),
+		errors.Is(err, repository.ErrAgentOnDelivery),
+		errors.Is(err, repository.ErrAgentInactive):
+		return c.Status(409).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(500).JSON(fiber.Map{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+func (h *ShiftHandler) publishStatus(c *fiber.Ctx, agentID, previous, status string) {
+	h.hub.Publish(realtime.Event{
+		Type:     realtime.EventStatus,
+		TenantID: auth.TenantID(c),
+		AgentID:  agentID,
+		Data: fiber.Map{
+			"agent_id":        agentID,
+			"previous_status": previous,
+			"status":          status,
+		},
+	})
+}
+
+// GetCoverage compares who is scheduled with who is online at the time in
+// the at query parameter, or now.
+func (h *ShiftHandler) GetCoverage(c *fiber.Ctx) error {
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z",
+			})
+		}
+		if t.After(at) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "at must not be in the future",
+			})
+		}
+		at = t
+	}
+
+	coverage, err := h.shiftService.Coverage(auth.TenantID(c), at)
+	if err != nil {
+		log.Printf("Failed to build shift coverage: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to build shift coverage",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    coverage,
+	})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultReportRange = 7 * 24 * time.Hour
+	maxReportRange     = 366 * 24 * time.Hour
+
+	// Free-text fields such as reasons and notes are stored in varchar(255)
+	// columns.
+	maxTextLength = 255
+)
+
+type StatusHandler struct {
+	historyRepo   *repository.StatusHistoryRepository
+	reportService *services.StatusReportService
+}
+
+func NewStatusHandler(historyRepo *repository.StatusHistoryRepository, reportService *services.StatusReportService) *StatusHandler {
+	return &StatusHandler{
+		historyRepo:   historyRepo,
+		reportService: reportService,
+	}
+}
+
+// tooLong reports whether value does not fit a varchar(maxTextLength) column.
+func tooLong(value string) bool {
+	return utf8.RuneCountInString(value) > maxTextLength
+}
+
+// statusTrigger describes the caller as the trigger of a status change.
+func statusTrigger(c *fiber.Ctx, reason string) models.StatusTrigger {
+	principal := auth.FromContext(c)
+
+	if principal != nil && principal.IsAgent() {
+		return models.StatusTrigger{
+			Source: models.StatusSourceAgent,
+			Actor:  principal.AgentID,
+			Reason: reason,
+		}
+	}
+
+	trigger := models.StatusTrigger{
+		Source: models.StatusSourceOperator,
+		Reason: reason,
+	}
+	if principal != nil {
+		trigger.Actor = principal.Email
+	}
+	return trigger
+}
+
+// parseTimeRange reads the from/to query parameters. Missing values default
+// to the last defaultRange ending now. It returns a non-empty message when
+// the range is invalid.
+func parseTimeRange(c *fiber.Ctx, defaultRange, maxRange time.Duration) (time.Time, time.Time, string) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z"
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultRange)
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid time format. Use RFC3339 format: 2024-12-07T10:30:00Z"
+		}
+		from = t
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, "to must be after from"
+	}
+
+	if to.Sub(from) > maxRange {
+		return time.Time{}, time.Time{}, fmt.Sprintf("range must not exceed %d days", int(maxRange.Hours()/24))
+	}
+
+	return from, to, ""
+}
+
+func (h *StatusHandler) GetAgentStatusHistory(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	from, to, msg := parseTimeRange(c, defaultReportRange, maxReportRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+	if limit < 1 {
+		limit = 100
+	}
+
+	changes, err := h.historyRepo.ForTenant(auth.TenantID(c)).FindByAgent(agentID, from, to, limit)
+	if err != nil {
+		log.Printf("Failed to fetch status history: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch status history",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.AgentStatusChangeResponse, 0, len(changes))
+	for _, change := range changes {
+		responses = append(responses, models.AgentStatusChangeResponse{
+			ID:         change.ID,
+			AgentID:    change.AgentID,
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			Source:     change.Source,
+			Actor:      change.Actor,
+			Reason:     change.Reason,
+			Timestamp:  change.Timestamp,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
+
+func (h *StatusHandler) GetAgentStatusReport(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	from, to, msg := parseTimeRange(c, defaultReportRange, maxReportRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	report, err := h.reportService.AgentReport(auth.TenantID(c), agentID, from, to)
+	if err != nil {
+		log.Printf("Failed to build status report: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to build status report",
+			"details": err.Error(),
+		})
+	}
+
+	if report == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    report,
+	})
+}
+
+func (h *StatusHandler) GetFleetStatusReport(c *fiber.Ctx) error {
+	from, to, msg := parseTimeRange(c, defaultReportRange, maxReportRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	report, err := h.reportService.FleetReport(auth.TenantID(c), from, to)
+	if err != nil {
+		log.Printf("Failed to build fleet status report: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to build status report",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    report,
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultStopRange = 24 * time.Hour
+	maxStopRange     = 7 * 24 * time.Hour
+)
+
+type StopHandler struct {
+	stopService *services.StopService
+}
+
+func NewStopHandler(stopService *services.StopService) *StopHandler {
+	return &StopHandler{
+		stopService: stopService,
+	}
+}
+
+// GetStops lists where the agent stopped over from/to (default: the last
+// 24 hours). radius_m and min_duration_s override the configured stop
+// definition for this request.
+func (h *StopHandler) GetStops(c *fiber.Ctx) error {
+	agentID := c.Params("id")
+
+	from, to, msg := parseTimeRange(c, defaultStopRange, maxStopRange)
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	cfg := h.stopService.Config()
+	cfg.RadiusM = c.QueryFloat("radius_m", cfg.RadiusM)
+	cfg.MinDuration = time.Duration(c.QueryInt("min_duration_s", int(cfg.MinDuration.Seconds()))) * time.Second
+
+	if cfg.RadiusM < 5 || cfg.RadiusM > 1000 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "radius_m must be between 5 and 1000",
+		})
+	}
+
+	if cfg.MinDuration < time.Minute || cfg.MinDuration > 24*time.Hour {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "min_duration_s must be between 60 and 86400",
+		})
+	}
+
+	report, err := h.stopService.Stops(auth.TenantID(c), agentID, from, to, cfg)
+	if err != nil {
+		log.Printf("Failed to detect stops for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to detect stops",
+			"details": err.Error(),
+		})
+	}
+
+	if report == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    report,
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/gofiber/fiber/v2"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+type StreamHandler struct {
+	hub *realtime.Hub
+}
+
+func NewStreamHandler(hub *realtime.Hub) *StreamHandler {
+	return &StreamHandler{
+		hub: hub,
+	}
+}
+
+// StreamEvents serves fleet-wide location, status and geofence events as
+// Server-Sent Events. Reconnecting clients send Last-Event-ID (or the
+// last_event_id query for clients that cannot set headers) and receive the
+// events they missed. When those are no longer buffered the stream opens
+// with a reset event instead, and the client must refetch current state.
+func (h *StreamHandler) StreamEvents(c *fiber.Ctx) error {
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Last-Event-ID must be a positive integer",
+			})
+		}
+		lastID = id
+	}
+
+	agentIDs := parseList(c.Query("agent_id"), c.Query("agent_ids"))
+	types := parseList(c.Query("types"))
+
+	for _, t := range types {
+		switch t {
+		case realtime.EventLocation, realtime.EventStatus, realtime.EventGeofence, realtime.EventMotion:
+		default:
+			return c.Status(400).JSON(fiber.Map{
+				"error": "types must be: location, status, geofence, motion",
+			})
+		}
+	}
+
+	sub, replay := h.hub.SubscribeFrom(lastID, auth.TenantID(c), agentIDs, types)
+
+	log.Printf("Event stream opened (agents=%v, types=%v, last_event_id=%d, replayed=%d, reset=%v)",
+		agentIDs, types, lastID, len(replay.Events), replay.Reset)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unsubscribe(sub)
+
+		fmt.Fprintf(w, "retry: %d\n\n", 3000)
+		if replay.Reset {
+			if err := writeSSEReset(w, replay.LastID); err != nil {
+				return
+			}
+		}
+		for _, event := range replay.Events {
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(sseHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					log.Printf("Event stream dropped slow consumer (agents=%v)", agentIDs)
+					return
+				}
+				if err := writeSSEEvent(w, event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func writeSSEEvent(w *bufio.Writer, event realtime.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+	return err
+}
+
+// writeSSEReset tells the client that events were missed. Its ID moves the
+// client's Last-Event-ID to the present so the next reconnect resumes
+// normally.
+func writeSSEReset(w *bufio.Writer, lastID uint64) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {\"last_id\":%d}\n\n", lastID, lastID)
+	return err
+}
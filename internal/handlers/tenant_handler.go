@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,63}$`)
+
+type TenantHandler struct {
+	tenantRepo *repository.TenantRepository
+}
+
+func NewTenantHandler(tenantRepo *repository.TenantRepository) *TenantHandler {
+	return &TenantHandler{
+		tenantRepo: tenantRepo,
+	}
+}
+
+func toTenantResponse(tenant models.Tenant) models.TenantResponse {
+	return models.TenantResponse{
+		ID:        tenant.ID,
+		Name:      tenant.Name,
+		IsActive:  tenant.IsActive,
+		CreatedAt: tenant.CreatedAt,
+	}
+}
+
+// CreateTenant creates a fleet together with its first admin, who then
+// manages the tenant's own users, agents and data.
+func (h *TenantHandler) CreateTenant(c *fiber.Ctx) error {
+	var req models.TenantRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	req.ID = strings.ToLower(req.ID)
+	if !tenantIDPattern.MatchString(req.ID) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "id must be 2-64 lowercase letters, digits or dashes",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	if req.AdminEmail == "" || !strings.Contains(req.AdminEmail, "@") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "a valid admin_email is required",
+		})
+	}
+
+	if len(req.AdminPassword) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "admin_password must be at least 8 characters",
+		})
+	}
+
+	hash, err := auth.HashPassword(req.AdminPassword)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create tenant",
+		})
+	}
+
+	adminName := req.AdminName
+	if adminName == "" {
+		adminName = "Administrator"
+	}
+
+	tenant := models.Tenant{
+		ID:       req.ID,
+		Name:     req.Name,
+		IsActive: true,
+	}
+
+	admin := models.User{
+		Email:        strings.ToLower(req.AdminEmail),
+		Name:         adminName,
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		IsActive:     true,
+	}
+
+	if err := h.tenantRepo.CreateWithAdmin(&tenant, &admin); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to create tenant",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Tenant created: ID=%s, Admin=%s", tenant.ID, admin.Email)
+
+	response := toTenantResponse(tenant)
+	adminResponse := toUserResponse(admin)
+	response.Admin = &adminResponse
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Tenant created successfully",
+		"data":    response,
+	})
+}
+
+func (h *TenantHandler) ListTenants(c *fiber.Ctx) error {
+	tenants, err := h.tenantRepo.FindAll()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch tenants",
+			"details": err.Error(),
+		})
+	}
+
+	responses := make([]models.TenantResponse, 0, len(tenants))
+	for _, tenant := range tenants {
+		responses = append(responses, toTenantResponse(tenant))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(responses),
+		"data":    responses,
+	})
+}
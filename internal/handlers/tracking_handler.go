@@ -1,24 +1,48 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
 	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
 	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 type TrackingHandler struct {
-	locationRepo *repository.LocationRepository
+	locationRepo    *repository.LocationRepository
+	agentRepo       *repository.AgentRepository
+	hub             *realtime.Hub
+	validator       *services.LocationValidator
+	geofenceService *services.GeofenceService
+	offlineDetector *services.OfflineDetector
+	motionService   *services.MotionService
 }
 
-func NewTrackingHandler(locationRepo *repository.LocationRepository) *TrackingHandler {
+func NewTrackingHandler(locationRepo *repository.LocationRepository, agentRepo *repository.AgentRepository, hub *realtime.Hub, validator *services.LocationValidator, geofenceService *services.GeofenceService, offlineDetector *services.OfflineDetector, motionService *services.MotionService) *TrackingHandler {
 	return &TrackingHandler{
-		locationRepo: locationRepo,
+		locationRepo:    locationRepo,
+		agentRepo:       agentRepo,
+		hub:             hub,
+		validator:       validator,
+		geofenceService: geofenceService,
+		offlineDetector: offlineDetector,
+		motionService:   motionService,
 	}
 }
 
+// calculateStatus labels a single fix from its reported speed; a negative
+// speed is how devices report an unknown one. Live views show the agent's
+// smoothed motion state instead, which needs several fixes to settle.
 func calculateStatus(speed float64) string {
 	switch {
 	case speed == 0:
@@ -32,7 +56,162 @@ func calculateStatus(speed float64) string {
 	}
 }
 
+// buildLocation validates an incoming request and returns the Location to
+// store. A non-empty reason means the point must be rejected.
+func (h *TrackingHandler) buildLocation(req models.LocationRequest, tenantID string) (models.Location, string) {
+	return buildLocationWith(h.validator, req, tenantID)
+}
+
+func buildLocationWith(validator *services.LocationValidator, req models.LocationRequest, tenantID string) (models.Location, string) {
+	location, reason := validator.Build(req)
+	if reason != "" {
+		return location, reason
+	}
+
+	location.TenantID = tenantID
+	location.Status = calculateStatus(location.Speed)
+	return location, ""
+}
+
+// ownsAgent reports whether the principal may report locations for the
+// agent. A device key is bound to its agent, which already belongs to the
+// tenant; operators may only report for their tenant's agents.
+func (h *TrackingHandler) ownsAgent(principal *auth.Principal, agentID string) (bool, error) {
+	if principal.IsAgent() {
+		return principal.AgentID == agentID, nil
+	}
+
+	agent, err := h.agentRepo.ForTenant(principal.TenantID).FindByID(agentID)
+	if err != nil {
+		return false, err
+	}
+
+	return agent != nil, nil
+}
+
+// assessLocations flags points that are implausible next to the agent's
+// previous fix. Points are compared in time order per agent, the first one
+// against the latest stored good fix before it and the suspect fixes stored
+// since.
+func (h *TrackingHandler) assessLocations(locations []models.Location) error {
+	order := make([]int, len(locations))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		la, lb := locations[order[a]], locations[order[b]]
+		if la.AgentID != lb.AgentID {
+			return la.AgentID < lb.AgentID
+		}
+		return la.Timestamp.Before(lb.Timestamp)
+	})
+
+	var previous *models.Location
+	var suspects []models.Location
+	agentID := ""
+	for _, i := range order {
+		location := &locations[i]
+
+		if agentID != location.AgentID {
+			agentID = location.AgentID
+
+			var err error
+			previous, suspects, err = h.storedReference(*location)
+			if err != nil {
+				return err
+			}
+		}
+
+		h.validator.Assess(location, previous, suspects)
+
+		if location.Quality == models.QualitySuspect {
+			suspects = append(suspects, *location)
+		} else {
+			previous = location
+			suspects = nil
+		}
+	}
+
+	return nil
+}
+
+// storedReference loads what Assess compares the location with: the
+// agent's latest stored good fix before it and the suspect fixes since.
+func (h *TrackingHandler) storedReference(location models.Location) (*models.Location, []models.Location, error) {
+	locationRepo := h.locationRepo.ForTenant(location.TenantID)
+
+	previous, err := locationRepo.FindPrevious(location.AgentID, location.Timestamp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	window := h.validator.SuspectWindow()
+	if window == 0 {
+		return previous, nil, nil
+	}
+
+	var since time.Time
+	if previous != nil {
+		since = previous.Timestamp
+	}
+
+	suspects, err := locationRepo.FindSuspectsBetween(location.AgentID, since, location.Timestamp, window)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return previous, suspects, nil
+}
+
+// locationsAccepted runs everything that reacts to newly stored points,
+// given in chronological order. Suspect fixes are still published but never
+// move the agent in or out of geofences or change its motion state, which is
+// evaluated once per agent for all the points. Failures here are logged and
+// never undo the ingestion.
+func (h *TrackingHandler) locationsAccepted(locations []models.Location) {
+	for _, location := range locations {
+		h.locationAccepted(location)
+	}
+
+	if _, err := h.motionService.EvaluateBatch(locations); err != nil {
+		log.Printf("Failed to evaluate motion states: %v", err)
+	}
+}
+
+func (h *TrackingHandler) locationAccepted(location models.Location) {
+	h.offlineDetector.Heartbeat(location)
+
+	if location.Quality != models.QualitySuspect {
+		if _, err := h.geofenceService.Evaluate(location); err != nil {
+			log.Printf("Failed to evaluate geofences for agent %s: %v", location.AgentID, err)
+		}
+	}
+
+	h.hub.Publish(realtime.Event{
+		Type:     realtime.EventLocation,
+		TenantID: location.TenantID,
+		AgentID:  location.AgentID,
+		Data: models.LocationResponse{
+			ID:        location.ID,
+			PointID:   location.PointIDValue(),
+			AgentID:   location.AgentID,
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Speed:     location.Speed,
+			Heading:   location.Heading,
+			Accuracy:  location.Accuracy,
+			Status:    location.Status,
+			Quality:   location.Quality,
+			Timestamp: location.Timestamp,
+			CreatedAt: location.CreatedAt,
+		},
+	})
+}
+
 func (h *TrackingHandler) UpdateLocation(c *fiber.Ctx) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
 	var req models.LocationRequest
 
 	if err := c.BodyParser(&req); err != nil {
@@ -42,37 +221,47 @@ func (h *TrackingHandler) UpdateLocation(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.AgentID == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "agent_id is required",
+	principal := auth.FromContext(c)
+	if principal != nil && principal.IsAgent() && req.AgentID == "" {
+		req.AgentID = principal.AgentID
+	}
+
+	if principal == nil || !principal.Can(auth.PermTrackingWrite, req.AgentID) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "agent_id does not match the authenticated device",
 		})
 	}
 
-	if req.Latitude == 0 || req.Longitude == 0 {
+	location, reason := h.buildLocation(req, principal.TenantID)
+	if reason != "" {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "latitude and longitude are required",
+			"error": reason,
 		})
 	}
+	status := location.Status
 
-	timestamp, err := time.Parse(time.RFC3339, req.Timestamp)
+	owned, err := h.ownsAgent(principal, location.AgentID)
 	if err != nil {
-		timestamp = time.Now()
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
 	}
 
-	status := calculateStatus(req.Speed)
+	if !owned {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
 
-	location := models.Location{
-		AgentID:   req.AgentID,
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
-		Speed:     req.Speed,
-		Heading:   req.Heading,
-		Accuracy:  req.Accuracy,
-		Status:    status,
-		Timestamp: timestamp,
+	assessed := []models.Location{location}
+	if err := h.assessLocations(assessed); err != nil {
+		log.Printf("Failed to assess location: %v", err)
 	}
+	location = assessed[0]
 
-	if err := h.locationRepo.Create(&location); err != nil {
+	duplicate, err := locationRepo.Create(&location)
+	if err != nil {
 		log.Printf("Failed to save location: %v", err)
 		return c.Status(500).JSON(fiber.Map{
 			"error":   "Failed to save location",
@@ -80,24 +269,52 @@ func (h *TrackingHandler) UpdateLocation(c *fiber.Ctx) error {
 		})
 	}
 
+	if duplicate {
+		log.Printf("Duplicate location ignored: Agent=%s, PointID=%s, ID=%d",
+			location.AgentID, location.PointIDValue(), location.ID)
+
+		return c.Status(200).JSON(fiber.Map{
+			"success":   true,
+			"duplicate": true,
+			"message":   "Location already recorded",
+			"data": fiber.Map{
+				"id":        location.ID,
+				"point_id":  location.PointIDValue(),
+				"agent_id":  location.AgentID,
+				"latitude":  location.Latitude,
+				"longitude": location.Longitude,
+				"status":    location.Status,
+				"quality":   location.Quality,
+				"timestamp": location.Timestamp,
+			},
+		})
+	}
+
 	log.Printf("Location saved: Agent=%s, Status=%s, Lat=%.6f, Lng=%.6f, Speed=%.2f km/h, ID=%d",
 		req.AgentID, status, req.Latitude, req.Longitude, req.Speed, location.ID) // 🔄 CHANGED log
 
+	h.locationsAccepted([]models.Location{location})
+
 	return c.Status(201).JSON(fiber.Map{
-		"success": true,
-		"message": "Location updated successfully",
+		"success":   true,
+		"duplicate": false,
+		"message":   "Location updated successfully",
 		"data": fiber.Map{
 			"id":        location.ID,
+			"point_id":  location.PointIDValue(),
 			"agent_id":  req.AgentID,
 			"latitude":  req.Latitude,
 			"longitude": req.Longitude,
 			"status":    status,
+			"quality":   location.Quality,
 			"timestamp": location.Timestamp,
 		},
 	})
 }
 
 func (h *TrackingHandler) GetLiveLocation(c *fiber.Ctx) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 
 	if agentID == "" {
@@ -106,7 +323,7 @@ func (h *TrackingHandler) GetLiveLocation(c *fiber.Ctx) error {
 		})
 	}
 
-	location, err := h.locationRepo.FindLatestByAgentID(agentID)
+	location, err := locationRepo.FindLatestByAgentID(agentID)
 	if err != nil {
 		log.Printf("Failed to fetch live location: %v", err)
 		return c.Status(500).JSON(fiber.Map{
@@ -126,6 +343,7 @@ func (h *TrackingHandler) GetLiveLocation(c *fiber.Ctx) error {
 
 	response := models.LocationResponse{
 		ID:        location.ID,
+		PointID:   location.PointIDValue(),
 		AgentID:   location.AgentID,
 		Latitude:  location.Latitude,
 		Longitude: location.Longitude,
@@ -133,6 +351,7 @@ func (h *TrackingHandler) GetLiveLocation(c *fiber.Ctx) error {
 		Heading:   location.Heading,
 		Accuracy:  location.Accuracy,
 		Status:    location.Status,
+		Quality:   location.Quality,
 		Timestamp: location.Timestamp,
 		CreatedAt: location.CreatedAt,
 	}
@@ -143,7 +362,147 @@ func (h *TrackingHandler) GetLiveLocation(c *fiber.Ctx) error {
 	})
 }
 
+const maxLiveLocationAgents = 1000
+
+// GetLiveLocations returns the latest fix of many agents at once: the whole
+// fleet, those matching status or vehicle_type, or a comma-separated
+// agent_ids list.
+func (h *TrackingHandler) GetLiveLocations(c *fiber.Ctx) error {
+	query := models.LiveLocationsQuery{
+		Status:      c.Query("status", ""),
+		VehicleType: c.Query("vehicle_type", ""),
+		MaxAgeSecs:  c.QueryInt("max_age_s", 0),
+	}
+
+	if ids := c.Query("agent_ids", ""); ids != "" {
+		query.AgentIDs = strings.Split(ids, ",")
+	}
+
+	return h.liveLocations(c, query)
+}
+
+// QueryLiveLocations is GetLiveLocations with the selection in the request
+// body, for agent lists too long to fit in a URL.
+func (h *TrackingHandler) QueryLiveLocations(c *fiber.Ctx) error {
+	var query models.LiveLocationsQuery
+
+	if err := c.BodyParser(&query); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	return h.liveLocations(c, query)
+}
+
+func (h *TrackingHandler) liveLocations(c *fiber.Ctx, query models.LiveLocationsQuery) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
+	if query.Status != "" && query.Status != "available" && query.Status != "busy" && query.Status != "offline" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be: available, busy, or offline",
+		})
+	}
+
+	if query.MaxAgeSecs < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "max_age_s must not be negative",
+		})
+	}
+
+	agentIDs := make([]string, 0, len(query.AgentIDs))
+	seen := make(map[string]bool, len(query.AgentIDs))
+	for _, id := range query.AgentIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		agentIDs = append(agentIDs, id)
+	}
+
+	if len(agentIDs) > maxLiveLocationAgents {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("at most %d agent_ids can be requested at once", maxLiveLocationAgents),
+		})
+	}
+
+	if len(query.AgentIDs) > 0 && len(agentIDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "agent_ids must contain at least one agent ID",
+		})
+	}
+
+	filter := repository.LatestLocationFilter{
+		AgentIDs:    agentIDs,
+		Status:      query.Status,
+		VehicleType: strings.ToLower(query.VehicleType),
+	}
+
+	if query.MaxAgeSecs > 0 {
+		filter.Since = time.Now().Add(-time.Duration(query.MaxAgeSecs) * time.Second)
+	}
+
+	latest, err := locationRepo.FindLatestWithAgents(filter)
+	if err != nil {
+		log.Printf("Failed to fetch live locations: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch live locations",
+			"details": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	locations := make([]models.LiveLocationResponse, 0, len(latest))
+
+	for _, location := range latest {
+		delete(seen, location.AgentID)
+
+		locations = append(locations, models.LiveLocationResponse{
+			AgentID:       location.AgentID,
+			Name:          location.AgentName,
+			Status:        location.AgentStatus,
+			VehicleType:   location.VehicleType,
+			Latitude:      location.Latitude,
+			Longitude:     location.Longitude,
+			Speed:         location.Speed,
+			Heading:       location.Heading,
+			Accuracy:      location.Accuracy,
+			MotionStatus:  location.MotionState,
+			Quality:       location.Quality,
+			FixAgeSeconds: int64(now.Sub(location.Timestamp).Seconds()),
+			Timestamp:     location.Timestamp,
+		})
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].AgentID < locations[j].AgentID
+	})
+
+	response := fiber.Map{
+		"success": true,
+		"count":   len(locations),
+		"data":    locations,
+	}
+
+	// Requested agents that are unknown, filtered out or have no fix
+	if len(agentIDs) > 0 {
+		missing := make([]string, 0, len(seen))
+		for _, id := range agentIDs {
+			if seen[id] {
+				missing = append(missing, id)
+			}
+		}
+		response["missing"] = missing
+	}
+
+	return c.JSON(response)
+}
+
 func (h *TrackingHandler) GetLocationHistory(c *fiber.Ctx) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
 	agentID := c.Params("id")
 
 	if agentID == "" {
@@ -176,9 +535,9 @@ func (h *TrackingHandler) GetLocationHistory(c *fiber.Ctx) error {
 			})
 		}
 
-		locations, err = h.locationRepo.FindByAgentIDAndTimeRange(agentID, startTime, endTime)
+		locations, err = locationRepo.FindByAgentIDAndTimeRange(agentID, startTime, endTime)
 	} else {
-		locations, err = h.locationRepo.FindByAgentID(agentID, limit)
+		locations, err = locationRepo.FindByAgentID(agentID, limit)
 	}
 
 	if err != nil {
@@ -201,6 +560,7 @@ func (h *TrackingHandler) GetLocationHistory(c *fiber.Ctx) error {
 	for _, loc := range locations {
 		responses = append(responses, models.LocationResponse{
 			ID:        loc.ID,
+			PointID:   loc.PointIDValue(),
 			AgentID:   loc.AgentID,
 			Latitude:  loc.Latitude,
 			Longitude: loc.Longitude,
@@ -208,6 +568,7 @@ func (h *TrackingHandler) GetLocationHistory(c *fiber.Ctx) error {
 			Heading:   loc.Heading,
 			Accuracy:  loc.Accuracy,
 			Status:    loc.Status,
+			Quality:   loc.Quality,
 			Timestamp: loc.Timestamp,
 			CreatedAt: loc.CreatedAt,
 		})
@@ -220,3 +581,322 @@ func (h *TrackingHandler) GetLocationHistory(c *fiber.Ctx) error {
 		"data":    responses,
 	})
 }
+
+const maxBatchSize = 500
+
+type pointKey struct {
+	agentID   string
+	timestamp int64
+}
+
+func keyOf(location models.Location) pointKey {
+	return pointKey{agentID: location.AgentID, timestamp: location.Timestamp.UnixMicro()}
+}
+
+type pointIDKey struct {
+	agentID string
+	pointID string
+}
+
+// pointIndex remembers points by both identities a duplicate can match on.
+type pointIndex struct {
+	byKey     map[pointKey]int
+	byPointID map[pointIDKey]int
+}
+
+func newPointIndex() *pointIndex {
+	return &pointIndex{
+		byKey:     make(map[pointKey]int),
+		byPointID: make(map[pointIDKey]int),
+	}
+}
+
+func (p *pointIndex) add(location models.Location, value int) {
+	p.byKey[keyOf(location)] = value
+	if location.PointID != nil {
+		p.byPointID[pointIDKey{agentID: location.AgentID, pointID: *location.PointID}] = value
+	}
+}
+
+func (p *pointIndex) find(location models.Location) (int, bool) {
+	if location.PointID != nil {
+		if value, ok := p.byPointID[pointIDKey{agentID: location.AgentID, pointID: *location.PointID}]; ok {
+			return value, true
+		}
+	}
+	value, ok := p.byKey[keyOf(location)]
+	return value, ok
+}
+
+// UpdateLocationsBatch ingests an array of buffered points. Each item is
+// validated on its own; valid, non-duplicate items are stored with one
+// multi-row insert and every item gets its own result.
+func (h *TrackingHandler) UpdateLocationsBatch(c *fiber.Ctx) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
+	var reqs []models.LocationRequest
+
+	if err := c.BodyParser(&reqs); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Invalid request body, expected an array of locations",
+			"details": err.Error(),
+		})
+	}
+
+	if len(reqs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "at least one location is required",
+		})
+	}
+
+	if len(reqs) > maxBatchSize {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("batch too large, maximum is %d locations", maxBatchSize),
+		})
+	}
+
+	results := make([]models.BatchLocationResult, len(reqs))
+	candidates := make([]models.Location, 0, len(reqs))
+	candidateIndex := make([]int, 0, len(reqs))
+	seen := newPointIndex()
+
+	principal := auth.FromContext(c)
+	owned := make(map[string]bool)
+
+	for i, req := range reqs {
+		if principal != nil && principal.IsAgent() && req.AgentID == "" {
+			req.AgentID = principal.AgentID
+		}
+
+		results[i] = models.BatchLocationResult{Index: i, AgentID: req.AgentID}
+
+		if principal == nil || !principal.Can(auth.PermTrackingWrite, req.AgentID) {
+			results[i].Status = models.BatchItemRejected
+			results[i].Reason = "agent_id does not match the authenticated device"
+			continue
+		}
+
+		location, reason := h.buildLocation(req, principal.TenantID)
+		if reason != "" {
+			results[i].Status = models.BatchItemRejected
+			results[i].Reason = reason
+			continue
+		}
+		results[i].Timestamp = &location.Timestamp
+
+		ok, checked := owned[location.AgentID]
+		if !checked {
+			var err error
+			ok, err = h.ownsAgent(principal, location.AgentID)
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{
+					"error":   "Failed to fetch agent",
+					"details": err.Error(),
+				})
+			}
+			owned[location.AgentID] = ok
+		}
+
+		if !ok {
+			results[i].Status = models.BatchItemRejected
+			results[i].Reason = "agent not found"
+			continue
+		}
+
+		if first, ok := seen.find(location); ok {
+			results[i].Status = models.BatchItemDuplicate
+			results[i].Reason = fmt.Sprintf("duplicate of item %d", first)
+			continue
+		}
+		seen.add(location, i)
+
+		candidates = append(candidates, location)
+		candidateIndex = append(candidateIndex, i)
+	}
+
+	existing, err := locationRepo.FindExisting(candidates)
+	if err != nil {
+		log.Printf("Failed to check existing locations: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to save locations",
+			"details": err.Error(),
+		})
+	}
+
+	stored := newPointIndex()
+	for _, loc := range existing {
+		stored.add(loc, int(loc.ID))
+	}
+
+	toInsert := make([]models.Location, 0, len(candidates))
+	insertIndex := make([]int, 0, len(candidates))
+	for j, location := range candidates {
+		i := candidateIndex[j]
+		if id, ok := stored.find(location); ok {
+			results[i].Status = models.BatchItemDuplicate
+			results[i].Reason = "location already stored"
+			results[i].ID = uint(id)
+			continue
+		}
+		toInsert = append(toInsert, location)
+		insertIndex = append(insertIndex, i)
+	}
+
+	if err := h.assessLocations(toInsert); err != nil {
+		log.Printf("Failed to assess location batch: %v", err)
+	}
+
+	duplicateFlags, err := locationRepo.CreateBatch(toInsert)
+	if err != nil {
+		log.Printf("Failed to save location batch: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to save locations",
+			"details": err.Error(),
+		})
+	}
+
+	var accepted []models.Location
+	for j, location := range toInsert {
+		i := insertIndex[j]
+		results[i].ID = location.ID
+		if duplicateFlags[j] {
+			results[i].Status = models.BatchItemDuplicate
+			results[i].Reason = "location already stored"
+			continue
+		}
+		results[i].Status = models.BatchItemAccepted
+		results[i].Quality = location.Quality
+		accepted = append(accepted, location)
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].Timestamp.Before(accepted[j].Timestamp)
+	})
+	h.locationsAccepted(accepted)
+
+	var rejected, duplicates int
+	for _, r := range results {
+		switch r.Status {
+		case models.BatchItemRejected:
+			rejected++
+		case models.BatchItemDuplicate:
+			duplicates++
+		}
+	}
+
+	log.Printf("Location batch processed: total=%d, accepted=%d, rejected=%d, duplicate=%d",
+		len(reqs), len(accepted), rejected, duplicates)
+
+	return c.Status(200).JSON(fiber.Map{
+		"success": len(accepted) > 0 || duplicates > 0,
+		"message": "Location batch processed",
+		"summary": fiber.Map{
+			"total":     len(reqs),
+			"accepted":  len(accepted),
+			"rejected":  rejected,
+			"duplicate": duplicates,
+		},
+		"data": results,
+	})
+}
+
+const maxNearbyRadiusM = 50000
+
+// GetNearbyAgents lists agents whose most recent fix lies within radius_m of
+// the given point, closest first.
+func (h *TrackingHandler) GetNearbyAgents(c *fiber.Ctx) error {
+	locationRepo := h.locationRepo.ForTenant(auth.TenantID(c))
+
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "valid lat and lng query parameters are required",
+		})
+	}
+
+	radius := c.QueryFloat("radius_m", 2000)
+	if radius <= 0 || radius > maxNearbyRadiusM {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("radius_m must be between 0 and %d", maxNearbyRadiusM),
+		})
+	}
+
+	status := c.Query("status", "")
+	if status != "" && status != "available" && status != "busy" && status != "offline" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be: available, busy, or offline",
+		})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit > 200 {
+		limit = 200
+	}
+	if limit < 1 {
+		limit = 50
+	}
+
+	filter := repository.LatestLocationFilter{
+		Status:      status,
+		VehicleType: strings.ToLower(c.Query("vehicle_type", "")),
+	}
+
+	bounds := geo.BoundingBox(lat, lng, radius)
+	filter.Bounds = &bounds
+
+	if maxAge := c.QueryInt("max_age_s", 0); maxAge > 0 {
+		filter.Since = time.Now().Add(-time.Duration(maxAge) * time.Second)
+	}
+
+	candidates, err := locationRepo.FindLatestWithAgents(filter)
+	if err != nil {
+		log.Printf("Failed to fetch nearby agents: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch nearby agents",
+			"details": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	nearby := make([]models.NearbyAgentResponse, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		distance := geo.HaversineMeters(lat, lng, candidate.Latitude, candidate.Longitude)
+		if distance > radius {
+			continue
+		}
+
+		nearby = append(nearby, models.NearbyAgentResponse{
+			AgentID:       candidate.AgentID,
+			Name:          candidate.AgentName,
+			Status:        candidate.AgentStatus,
+			VehicleType:   candidate.VehicleType,
+			Latitude:      candidate.Latitude,
+			Longitude:     candidate.Longitude,
+			Speed:         candidate.Speed,
+			Heading:       candidate.Heading,
+			MotionStatus:  candidate.MotionState,
+			DistanceM:     math.Round(distance*10) / 10,
+			FixAgeSeconds: int64(now.Sub(candidate.Timestamp).Seconds()),
+			Timestamp:     candidate.Timestamp,
+		})
+	}
+
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i].DistanceM < nearby[j].DistanceM
+	})
+
+	if len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+
+	log.Printf("Found %d agents within %.0f m of (%.6f, %.6f)", len(nearby), radius, lat, lng)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(nearby),
+		"data":    nearby,
+	})
+}
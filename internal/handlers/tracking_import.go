@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxImportSkippedRows = 200
+
+type importCandidate struct {
+	row      int
+	location models.Location
+}
+
+// ImportLocations backfills the agent's history from an uploaded CSV or GPX
+// file. Each point goes through the same validation as UpdateLocation,
+// except that old fixes are accepted and timestamps are required.
+// Duplicates of stored points or of earlier rows are skipped. Imported
+// points are historical, so they do not trigger geofence events or
+// realtime updates.
+func (h *TrackingHandler) ImportLocations(c *fiber.Ctx) error {
+	tenantID := auth.TenantID(c)
+	agentID := c.Params("id")
+
+	agent, err := h.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to fetch agent",
+			"details": err.Error(),
+		})
+	}
+
+	if agent == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "a CSV or GPX file is required in the file field",
+		})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	if format != "csv" && format != "gpx" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be: csv or gpx",
+		})
+	}
+
+	var mapping models.CSVMapping
+	if value := c.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Invalid mapping",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to read file",
+			"details": err.Error(),
+		})
+	}
+	defer file.Close()
+
+	var rows []services.ImportRow
+	if format == "csv" {
+		rows, err = services.ParseLocationCSV(file, mapping)
+	} else {
+		rows, err = services.ParseLocationGPX(file)
+	}
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Failed to parse file",
+			"details": err.Error(),
+		})
+	}
+
+	summary, err := h.importRows(tenantID, agentID, rows)
+	if err != nil {
+		log.Printf("Failed to import locations for agent %s: %v", agentID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to import locations",
+			"details": err.Error(),
+		})
+	}
+	summary.Format = format
+
+	log.Printf("Location import for agent %s: rows=%d, imported=%d, rejected=%d, duplicate=%d",
+		agentID, summary.TotalRows, summary.Imported, summary.Rejected, summary.Duplicates)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Location import processed",
+		"data":    summary,
+	})
+}
+
+func (h *TrackingHandler) importRows(tenantID, agentID string, rows []services.ImportRow) (*models.LocationImportSummary, error) {
+	locationRepo := h.locationRepo.ForTenant(tenantID)
+	validator := h.validator.ForBackfill()
+
+	summary := &models.LocationImportSummary{
+		AgentID:   agentID,
+		TotalRows: len(rows),
+		Skipped:   []models.ImportSkippedRow{},
+	}
+
+	skip := func(row int, reason string) {
+		if len(summary.Skipped) < maxImportSkippedRows {
+			summary.Skipped = append(summary.Skipped, models.ImportSkippedRow{Row: row, Reason: reason})
+		} else {
+			summary.SkippedTruncated = true
+		}
+	}
+
+	candidates := make([]importCandidate, 0, len(rows))
+	seen := newPointIndex()
+
+	for _, row := range rows {
+		if row.Error != "" {
+			summary.Rejected++
+			skip(row.Row, row.Error)
+			continue
+		}
+
+		req := row.Request
+		req.AgentID = agentID
+
+		location, reason := buildLocationWith(validator, req, tenantID)
+		if reason != "" {
+			summary.Rejected++
+			skip(row.Row, reason)
+			continue
+		}
+
+		if first, ok := seen.find(location); ok {
+			summary.Duplicates++
+			skip(row.Row, fmt.Sprintf("duplicate of row %d", first))
+			continue
+		}
+		seen.add(location, row.Row)
+
+		candidates = append(candidates, importCandidate{row: row.Row, location: location})
+	}
+
+	// Store in time order so each point is assessed against the one before.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].location.Timestamp.Before(candidates[j].location.Timestamp)
+	})
+
+	for start := 0; start < len(candidates); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		chunk := candidates[start:end]
+
+		points := make([]models.Location, len(chunk))
+		for i, candidate := range chunk {
+			points[i] = candidate.location
+		}
+
+		existing, err := locationRepo.FindExisting(points)
+		if err != nil {
+			return nil, err
+		}
+
+		stored := newPointIndex()
+		for _, loc := range existing {
+			stored.add(loc, int(loc.ID))
+		}
+
+		toInsert := make([]models.Location, 0, len(chunk))
+		insertRows := make([]int, 0, len(chunk))
+		for _, candidate := range chunk {
+			if _, ok := stored.find(candidate.location); ok {
+				summary.Duplicates++
+				skip(candidate.row, "location already stored")
+				continue
+			}
+			toInsert = append(toInsert, candidate.location)
+			insertRows = append(insertRows, candidate.row)
+		}
+
+		if err := h.assessLocations(toInsert); err != nil {
+			return nil, err
+		}
+
+		duplicateFlags, err := locationRepo.CreateBatch(toInsert)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, location := range toInsert {
+			if duplicateFlags[i] {
+				summary.Duplicates++
+				skip(insertRows[i], "location already stored")
+				continue
+			}
+
+			summary.Imported++
+			if location.Quality == models.QualitySuspect {
+				summary.Suspect++
+			}
+
+			timestamp := location.Timestamp
+			if summary.From == nil || timestamp.Before(*summary.From) {
+				summary.From = &timestamp
+			}
+			if summary.To == nil || timestamp.After(*summary.To) {
+				summary.To = &timestamp
+			}
+		}
+	}
+
+	sort.SliceStable(summary.Skipped, func(i, j int) bool {
+		return summary.Skipped[i].Row < summary.Skipped[j].Row
+	})
+
+	return summary, nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/auth"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	streamPingInterval = 30 * time.Second
+	streamPongWait     = 60 * time.Second
+	streamWriteWait    = 10 * time.Second
+	streamReadLimit    = 4096
+)
+
+type streamMessage struct {
+	Action   string   `json:"action"`
+	AgentIDs []string `json:"agent_ids"`
+}
+
+// StreamLocations upgrades to a WebSocket and pushes every accepted location
+// for the requested agents. With no agent_id/agent_ids query the client
+// receives the whole fleet. Clients may change the filter at any time by
+// sending {"action":"subscribe","agent_ids":[...]}.
+func (h *TrackingHandler) StreamLocations(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(426).JSON(fiber.Map{
+			"error": "WebSocket upgrade required",
+		})
+	}
+
+	tenantID := auth.TenantID(c)
+	agentIDs := parseList(c.Query("agent_id"), c.Query("agent_ids"))
+
+	return websocket.New(func(conn *websocket.Conn) {
+		h.streamLocations(conn, tenantID, agentIDs)
+	})(c)
+}
+
+func (h *TrackingHandler) streamLocations(conn *websocket.Conn, tenantID string, agentIDs []string) {
+	sub := h.hub.Subscribe(tenantID, agentIDs, []string{realtime.EventLocation})
+	defer h.hub.Unsubscribe(sub)
+
+	log.Printf("Location stream opened (agents=%v, subscribers=%d)", agentIDs, h.hub.SubscriberCount())
+
+	conn.SetReadLimit(streamReadLimit)
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(streamPongWait))
+
+			var msg streamMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				continue
+			}
+			if msg.Action == "subscribe" {
+				sub.SetAgents(msg.AgentIDs)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				log.Printf("Location stream dropped slow consumer (agents=%v)", agentIDs)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+					time.Now().Add(streamWriteWait))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// parseList splits comma separated query values into a flat list.
+func parseList(values ...string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
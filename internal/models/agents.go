@@ -4,8 +4,9 @@ import "time"
 
 type DeliveryAgent struct {
 	ID          string    `gorm:"primaryKey" json:"id"`                      // AGENT001, AGENT002, etc.
+	TenantID    string    `gorm:"type:varchar(64);not null;default:'default';index;uniqueIndex:idx_agents_tenant_phone" json:"tenant_id"` // Owning fleet
 	Name        string    `gorm:"not null" json:"name"`                      // Full name
-	Phone       string    `gorm:"unique;not null" json:"phone"`              // Contact number (unique)
+	Phone       string    `gorm:"not null;uniqueIndex:idx_agents_tenant_phone" json:"phone"` // Contact number (unique per tenant)
 	Email       string    `gorm:"unique" json:"email"`                       // Email address
 	VehicleType string    `gorm:"type:varchar(20)" json:"vehicle_type"`      // bike, scooter, car, truck
 	Status      string    `gorm:"type:varchar(20);default:'offline'" json:"status"` // available, busy, offline
@@ -28,6 +29,7 @@ type AgentRequest struct {
 
 type AgentResponse struct {
 	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
 	Name        string    `json:"name"`
 	Phone       string    `json:"phone"`
 	Email       string    `json:"email"`
@@ -40,6 +42,7 @@ type AgentResponse struct {
 
 type AgentStatusUpdate struct {
 	Status string `json:"status" validate:"required,oneof=available busy offline"` // Only these values allowed
+	Reason string `json:"reason"`                                                  // Optional, kept in the status history
 }
 
 type AgentStats struct {
@@ -47,6 +50,17 @@ type AgentStats struct {
 	TotalDeliveries  int     `json:"total_deliveries"`
 	TotalDistance    float64 `json:"total_distance_km"`
 	AverageRating    float64 `json:"average_rating"`
+	RecentRating     float64 `json:"recent_average_rating"` // Over the last 30 days
+	RatingCount      int64   `json:"rating_count"`
 	TotalEarnings    float64 `json:"total_earnings"`
 	ActiveSince      string  `json:"active_since"`
+	Window           *StatsWindow `json:"window,omitempty"` // Set when from/to are requested
+}
+
+type StatsWindow struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Distance float64   `json:"distance_km"`
+	Earnings float64   `json:"earnings"`
+	Rating   float64   `json:"average_rating"`
 }
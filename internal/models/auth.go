@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+const (
+	RoleAdmin      = "admin"
+	RoleDispatcher = "dispatcher"
+	RoleViewer     = "viewer"
+	RoleAgent      = "agent" // Held by agent devices, never assigned to users
+)
+
+// IsValidUserRole reports whether role can be assigned to a user.
+func IsValidUserRole(role string) bool {
+	return role == RoleAdmin || role == RoleDispatcher || role == RoleViewer
+}
+
+// User is an operator who signs in to the dashboard.
+type User struct {
+	ID           uint      `gorm:"primaryKey"`
+	TenantID     string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	Email        string    `gorm:"uniqueIndex;not null"`
+	Name         string    `gorm:"not null"`
+	PasswordHash string    `gorm:"not null"`
+	Role         string    `gorm:"type:varchar(20);not null;default:'viewer'"`
+	IsActive     bool      `gorm:"default:true"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// RefreshToken is a long-lived, single-use token a user exchanges for a new
+// access token. Only its hash is stored.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// AgentCredential is an API key a delivery agent's device uses to report
+// locations. The key is shown once at creation; only its hash is stored.
+type AgentCredential struct {
+	ID         uint   `gorm:"primaryKey"`
+	TenantID   string `gorm:"type:varchar(64);not null;default:'default'"`
+	AgentID    string `gorm:"index;not null"`
+	Name       string // e.g. device model
+	KeyPrefix  string `gorm:"uniqueIndex;not null"`
+	KeyHash    string `gorm:"not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (AgentCredential) TableName() string {
+	return "agent_credentials"
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role"` // admin, dispatcher or viewer; defaults to viewer
+}
+
+type UserRoleUpdate struct {
+	Role string `json:"role"`
+}
+
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TokenResponse struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type CredentialRequest struct {
+	Name string `json:"name"`
+}
+
+type CredentialResponse struct {
+	ID         uint       `json:"id"`
+	AgentID    string     `json:"agent_id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	APIKey     string     `json:"api_key,omitempty"` // Only returned when the key is created
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
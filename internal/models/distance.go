@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AgentDistance is the running total of distance travelled by an agent,
+// folded forward from the last location it has seen.
+type AgentDistance struct {
+	AgentID       string    `gorm:"primaryKey"`
+	TotalMeters   float64   `gorm:"not null;default:0"`
+	AnchorLat     float64   `gorm:"type:decimal(10,8)"` // Last point that counted towards the total
+	AnchorLng     float64   `gorm:"type:decimal(11,8)"`
+	HasAnchor     bool      `gorm:"default:false"`
+	LastTimestamp time.Time // Timestamp of the newest location folded in
+	LastID        uint      // ID of that location, to break timestamp ties
+	MaxID         uint      // Highest location ID folded in, to detect points stored out of order
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (AgentDistance) TableName() string {
+	return "agent_distances"
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Kinds of earnings ledger entries.
+const (
+	EarningDelivery    = "delivery"     // Pay for a delivered order
+	EarningTargetBonus = "target_bonus" // Reaching a daily delivery target
+	EarningBonus       = "bonus"        // Granted by an operator
+	EarningAdjustment  = "adjustment"   // Operator correction, may be negative
+)
+
+const (
+	PayoutPending = "pending"
+	PayoutPaid    = "paid"
+)
+
+// PeakWindow raises delivery pay during busy hours. Times are HH:MM in the
+// pay rule's timezone; a window may wrap past midnight.
+type PeakWindow struct {
+	Days       []time.Weekday `json:"days,omitempty"` // Empty means every day
+	Start      string         `json:"start"`
+	End        string         `json:"end"`
+	Multiplier float64        `json:"multiplier"`
+}
+
+type PeakWindows []PeakWindow
+
+func (p PeakWindows) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+func (p *PeakWindows) Scan(value interface{}) error {
+	return scanJSON(value, p)
+}
+
+// DeliveryTarget pays a one-off bonus once an agent completes the given
+// number of deliveries in a day.
+type DeliveryTarget struct {
+	Deliveries int     `json:"deliveries"`
+	Bonus      float64 `json:"bonus"`
+}
+
+type DeliveryTargets []DeliveryTarget
+
+func (t DeliveryTargets) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+func (t *DeliveryTargets) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+	return errors.New("unsupported JSON value")
+}
+
+// PayRule prices deliveries for one vehicle type of a tenant. The rule with
+// an empty vehicle type applies to vehicles without a rule of their own.
+type PayRule struct {
+	ID              uint            `gorm:"primaryKey"`
+	TenantID        string          `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_pay_rules_tenant_vehicle"`
+	VehicleType     string          `gorm:"type:varchar(20);not null;default:'';uniqueIndex:idx_pay_rules_tenant_vehicle"`
+	BasePerDelivery float64         `gorm:"type:decimal(10,2);not null;default:0"`
+	PerKm           float64         `gorm:"type:decimal(10,2);not null;default:0"`
+	Timezone        string          `gorm:"type:varchar(64);not null;default:'UTC'"` // Used for peak hours and daily targets
+	PeakWindows     PeakWindows     `gorm:"type:jsonb"`
+	DailyTargets    DeliveryTargets `gorm:"type:jsonb"`
+	IsActive        bool            `gorm:"default:true"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time       `gorm:"autoUpdateTime"`
+}
+
+func (PayRule) TableName() string {
+	return "pay_rules"
+}
+
+type PayRuleRequest struct {
+	VehicleType     string           `json:"vehicle_type"` // Empty for the default rule
+	BasePerDelivery float64          `json:"base_per_delivery"`
+	PerKm           float64          `json:"per_km"`
+	Timezone        string           `json:"timezone"`
+	PeakWindows     []PeakWindow     `json:"peak_windows"`
+	DailyTargets    []DeliveryTarget `json:"daily_targets"`
+	IsActive        *bool            `json:"is_active"`
+}
+
+type PayRuleResponse struct {
+	ID              uint             `json:"id"`
+	VehicleType     string           `json:"vehicle_type"`
+	BasePerDelivery float64          `json:"base_per_delivery"`
+	PerKm           float64          `json:"per_km"`
+	Timezone        string           `json:"timezone"`
+	PeakWindows     []PeakWindow     `json:"peak_windows"`
+	DailyTargets    []DeliveryTarget `json:"daily_targets"`
+	IsActive        bool             `json:"is_active"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// EarningsEntry is one line of an agent's earnings ledger. Generated entries
+// carry a unique reference so the same delivery or target is never paid
+// twice.
+type EarningsEntry struct {
+	ID          uint    `gorm:"primaryKey"`
+	TenantID    string  `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID     string  `gorm:"index:idx_earnings_agent_time;not null"`
+	Type        string  `gorm:"type:varchar(20);not null"`
+	Reference   *string `gorm:"type:varchar(120);uniqueIndex"` // e.g. order:42, target:AGENT001:2024-12-07:10
+	OrderID     *uint   `gorm:"index"`
+	PayRuleID   *uint
+	Base        float64   `gorm:"type:decimal(10,2);default:0"`
+	DistanceKm  float64   `gorm:"type:decimal(10,3);default:0"`
+	DistancePay float64   `gorm:"type:decimal(10,2);default:0"`
+	Multiplier  float64   `gorm:"type:decimal(5,2);default:1"`
+	Amount      float64   `gorm:"type:decimal(12,2);not null"`
+	Description string    `gorm:"type:varchar(255)"`
+	CreatedBy   string    `gorm:"type:varchar(120)"`
+	PayoutID    *uint     `gorm:"index"` // Set once the entry is included in a payout
+	EarnedAt    time.Time `gorm:"index:idx_earnings_agent_time;not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (EarningsEntry) TableName() string {
+	return "earnings_entries"
+}
+
+type EarningsEntryResponse struct {
+	ID          uint      `json:"id"`
+	Type        string    `json:"type"`
+	OrderID     *uint     `json:"order_id,omitempty"`
+	Base        float64   `json:"base"`
+	DistanceKm  float64   `json:"distance_km"`
+	DistancePay float64   `json:"distance_pay"`
+	Multiplier  float64   `json:"multiplier"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	PayoutID    *uint     `json:"payout_id,omitempty"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+type EarningsAdjustmentRequest struct {
+	Type     string     `json:"type"` // bonus or adjustment
+	Amount   float64    `json:"amount"`
+	Reason   string     `json:"reason"`
+	EarnedAt *time.Time `json:"earned_at"` // Defaults to now
+}
+
+// Payout is a statement of the unpaid ledger entries of an agent over a
+// period.
+type Payout struct {
+	ID          uint      `gorm:"primaryKey"`
+	TenantID    string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID     string    `gorm:"index;not null"`
+	PeriodStart time.Time `gorm:"not null"`
+	PeriodEnd   time.Time `gorm:"not null"`
+	Amount      float64   `gorm:"type:decimal(12,2);not null"`
+	EntryCount  int       `gorm:"not null"`
+	Status      string    `gorm:"type:varchar(20);not null;default:'pending'"`
+	CreatedBy   string    `gorm:"type:varchar(120)"`
+	PaidAt      *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Payout) TableName() string {
+	return "payouts"
+}
+
+type PayoutRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type PayoutResponse struct {
+	ID          uint                    `json:"id"`
+	AgentID     string                  `json:"agent_id"`
+	PeriodStart time.Time               `json:"period_start"`
+	PeriodEnd   time.Time               `json:"period_end"`
+	Amount      float64                 `json:"amount"`
+	EntryCount  int                     `json:"entry_count"`
+	Status      string                  `json:"status"`
+	CreatedBy   string                  `json:"created_by,omitempty"`
+	PaidAt      *time.Time              `json:"paid_at,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	ByType      map[string]float64      `json:"by_type,omitempty"`
+	Entries     []EarningsEntryResponse `json:"entries,omitempty"`
+}
+
+// EarningsSummary totals an agent's ledger over a range.
+type EarningsSummary struct {
+	AgentID    string                  `json:"agent_id"`
+	From       time.Time               `json:"from"`
+	To         time.Time               `json:"to"`
+	Total      float64                 `json:"total"`
+	ByType     map[string]float64      `json:"by_type"`
+	Deliveries int                     `json:"deliveries"`
+	Unpaid     float64                 `json:"unpaid"` // All unpaid earnings, regardless of range
+	Entries    []EarningsEntryResponse `json:"entries"`
+}
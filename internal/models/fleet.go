@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// FleetSummary is a snapshot of the whole fleet for dashboards.
+type FleetSummary struct {
+	GeneratedAt   time.Time        `json:"generated_at"`
+	TotalAgents   int64            `json:"total_agents"` // Active agents
+	ByStatus      map[string]int64 `json:"by_status"`
+	ByVehicleType map[string]int64 `json:"by_vehicle_type"`
+	Motion        map[string]int64 `json:"motion"` // Smoothed motion state of the agents with a fresh fix
+	Locations     FleetLocations   `json:"locations"`
+	Orders        FleetOrders      `json:"orders"`
+}
+
+type FleetLocations struct {
+	Fresh          int64 `json:"fresh"`
+	Stale          int64 `json:"stale"`   // Last fix older than StaleAfterSecs
+	Missing        int64 `json:"missing"` // Never reported a location
+	StaleAfterSecs int64 `json:"stale_after_seconds"`
+}
+
+type FleetOrders struct {
+	Active     int64            `json:"active"`     // Assigned, picked up or in transit
+	Unassigned int64            `json:"unassigned"` // Created and waiting for an agent
+	ByStatus   map[string]int64 `json:"by_status"`
+}
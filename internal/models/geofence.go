@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	GeofenceCircle  = "circle"
+	GeofencePolygon = "polygon"
+
+	GeofenceEnter = "enter"
+	GeofenceExit  = "exit"
+	GeofenceDwell = "dwell"
+)
+
+// Polygon is a list of [latitude, longitude] vertices stored as JSON.
+type Polygon [][2]float64
+
+func (p Polygon) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	return string(b), err
+}
+
+func (p *Polygon) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return errors.New("unsupported polygon value")
+}
+
+type Geofence struct {
+	ID           uint      `gorm:"primaryKey"`
+	TenantID     string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	Name         string    `gorm:"not null"`
+	Category     string    `gorm:"type:varchar(30)"`          // warehouse, restaurant, restricted, ...
+	Shape        string    `gorm:"type:varchar(10);not null"` // circle, polygon
+	CenterLat    float64   `gorm:"type:decimal(10,8)"`
+	CenterLng    float64   `gorm:"type:decimal(11,8)"`
+	RadiusM      float64   `gorm:"type:decimal(10,2)"`
+	Vertices     Polygon   `gorm:"type:jsonb"`
+	DwellSeconds int       `gorm:"default:0"` // 0 disables dwell events
+	IsActive     bool      `gorm:"default:true"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Geofence) TableName() string {
+	return "geofences"
+}
+
+// GeofenceState tracks whether an agent is currently inside a geofence.
+type GeofenceState struct {
+	AgentID       string `gorm:"primaryKey"`
+	GeofenceID    uint   `gorm:"primaryKey"`
+	Inside        bool   `gorm:"not null"`
+	EnteredAt     *time.Time
+	DwellNotified bool      `gorm:"default:false"`
+	LastSeenAt    time.Time `gorm:"not null"`
+}
+
+func (GeofenceState) TableName() string {
+	return "geofence_states"
+}
+
+type GeofenceEvent struct {
+	ID         uint      `gorm:"primaryKey"`
+	TenantID   string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	GeofenceID uint      `gorm:"index;not null"`
+	AgentID    string    `gorm:"index;not null"`
+	EventType  string    `gorm:"type:varchar(10);not null"` // enter, exit, dwell
+	Latitude   float64   `gorm:"type:decimal(10,8);not null"`
+	Longitude  float64   `gorm:"type:decimal(11,8);not null"`
+	DwellSecs  int       `gorm:"default:0"` // Time spent inside, set on exit and dwell
+	Timestamp  time.Time `gorm:"index;not null"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (GeofenceEvent) TableName() string {
+	return "geofence_events"
+}
+
+type GeofenceRequest struct {
+	Name         string       `json:"name"`
+	Category     string       `json:"category"`
+	Shape        string       `json:"shape"` // circle, polygon
+	CenterLat    float64      `json:"center_lat"`
+	CenterLng    float64      `json:"center_lng"`
+	RadiusM      float64      `json:"radius_m"`
+	Vertices     [][2]float64 `json:"vertices"` // [[lat, lng], ...] for polygons
+	DwellSeconds int          `json:"dwell_seconds"`
+	IsActive     *bool        `json:"is_active"`
+}
+
+type GeofenceResponse struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	Category     string       `json:"category"`
+	Shape        string       `json:"shape"`
+	CenterLat    float64      `json:"center_lat,omitempty"`
+	CenterLng    float64      `json:"center_lng,omitempty"`
+	RadiusM      float64      `json:"radius_m,omitempty"`
+	Vertices     [][2]float64 `json:"vertices,omitempty"`
+	DwellSeconds int          `json:"dwell_seconds"`
+	IsActive     bool         `json:"is_active"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type GeofenceEventResponse struct {
+	ID         uint      `json:"id"`
+	GeofenceID uint      `json:"geofence_id"`
+	AgentID    string    `json:"agent_id"`
+	EventType  string    `json:"event_type"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	DwellSecs  int       `json:"dwell_seconds,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
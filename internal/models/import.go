@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CSVMapping names the CSV columns holding each field of a fix. Empty names
+// fall back to the defaults. When the header lacks an optional column, speed
+// and heading are left unknown (-1) and accuracy at zero.
+type CSVMapping struct {
+	Latitude        string `json:"latitude"`
+	Longitude       string `json:"longitude"`
+	Timestamp       string `json:"timestamp"`
+	Speed           string `json:"speed"`
+	Heading         string `json:"heading"`
+	Accuracy        string `json:"accuracy"`
+	PointID         string `json:"point_id"`
+	TimestampFormat string `json:"timestamp_format"` // rfc3339 (default), unix, unix_ms or a Go time layout in UTC
+	SpeedUnit       string `json:"speed_unit"`       // kmh (default), ms or mph
+	Delimiter       string `json:"delimiter"`        // Defaults to a comma
+}
+
+type ImportSkippedRow struct {
+	Row    int    `json:"row"` // CSV line or GPX point number, starting at 1
+	Reason string `json:"reason"`
+}
+
+type LocationImportSummary struct {
+	AgentID          string             `json:"agent_id"`
+	Format           string             `json:"format"`
+	TotalRows        int                `json:"total_rows"`
+	Imported         int                `json:"imported"`
+	Rejected         int                `json:"rejected"`
+	Duplicates       int                `json:"duplicates"`
+	Suspect          int                `json:"suspect"` // Imported but flagged as implausible
+	From             *time.Time         `json:"from,omitempty"`
+	To               *time.Time         `json:"to,omitempty"`
+	Skipped          []ImportSkippedRow `json:"skipped"`
+	SkippedTruncated bool               `json:"skipped_truncated"`
+}
@@ -3,6 +3,7 @@ package models
 import "time"
 
 type LocationRequest struct {
+	PointID   string  `json:"point_id"` // Optional client-generated ID used for idempotent retries
 	AgentID   string  `json:"agent_id"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
@@ -13,24 +14,42 @@ type LocationRequest struct {
 }
 
 type Location struct {
-	ID        uint      `gorm:"primaryKey"`
-	AgentID   string    `gorm:"index;not null"`
-	Latitude  float64   `gorm:"type:decimal(10,8);not null"`
-	Longitude float64   `gorm:"type:decimal(11,8);not null"`
-	Speed     float64   `gorm:"type:decimal(6,2)"`
-	Heading   float64   `gorm:"type:decimal(5,2)"`
-	Accuracy  float64   `gorm:"type:decimal(6,2)"`
-	Status    string    `gorm:"type:varchar(20);default:'unknown'"`
-	Timestamp time.Time `gorm:"index;not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID          uint      `gorm:"primaryKey"`
+	TenantID    string    `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_locations_tenant_agent_point,priority:1"`
+	PointID     *string   `gorm:"type:varchar(64);uniqueIndex:idx_locations_tenant_agent_point,priority:3"`
+	AgentID     string    `gorm:"index;uniqueIndex:idx_locations_agent_timestamp;uniqueIndex:idx_locations_tenant_agent_point,priority:2;not null"`
+	Latitude    float64   `gorm:"type:decimal(10,8);not null"`
+	Longitude   float64   `gorm:"type:decimal(11,8);not null"`
+	Speed       float64   `gorm:"type:decimal(6,2)"`
+	Heading     float64   `gorm:"type:decimal(5,2)"`
+	Accuracy    float64   `gorm:"type:decimal(6,2)"`
+	Status      string    `gorm:"type:varchar(20);default:'unknown'"`
+	Quality     string    `gorm:"type:varchar(20);default:'good'"` // good, low_accuracy, suspect
+	QualityNote string    `gorm:"type:varchar(120)"`
+	Timestamp   time.Time `gorm:"index;uniqueIndex:idx_locations_agent_timestamp;not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
 }
 
 func (Location) TableName() string {
 	return "locations"
 }
 
+const (
+	QualityGood        = "good"
+	QualityLowAccuracy = "low_accuracy"
+	QualitySuspect     = "suspect"
+)
+
+func (l Location) PointIDValue() string {
+	if l.PointID == nil {
+		return ""
+	}
+	return *l.PointID
+}
+
 type LocationResponse struct {
 	ID        uint      `json:"id"`
+	PointID   string    `json:"point_id,omitempty"`
 	AgentID   string    `json:"agent_id"`
 	Latitude  float64   `json:"latitude"`
 	Longitude float64   `json:"longitude"`
@@ -39,6 +58,74 @@ type LocationResponse struct {
 	Accuracy  float64   `json:"accuracy"`
 	Altitude  float64   `json:"altitude"`
 	Status    string    `json:"status"`
+	Quality   string    `json:"quality"`
 	Timestamp time.Time `json:"timestamp"`
 	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	BatchItemAccepted  = "accepted"
+	BatchItemRejected  = "rejected"
+	BatchItemDuplicate = "duplicate"
+)
+
+type BatchLocationResult struct {
+	Index     int        `json:"index"`
+	Status    string     `json:"status"` // accepted, rejected, duplicate
+	Reason    string     `json:"reason,omitempty"`
+	ID        uint       `json:"id,omitempty"`
+	AgentID   string     `json:"agent_id,omitempty"`
+	Quality   string     `json:"quality,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// AgentLocation is an agent's latest location joined with its profile.
+// MotionState is the agent's smoothed motion state, or the latest fix's own
+// motion status while no smoothed state is known.
+type AgentLocation struct {
+	Location    `gorm:"embedded"`
+	AgentName   string
+	AgentStatus string
+	VehicleType string
+	MotionState string
+}
+
+type NearbyAgentResponse struct {
+	AgentID       string    `json:"agent_id"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	VehicleType   string    `json:"vehicle_type"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	Speed         float64   `json:"speed"`
+	Heading       float64   `json:"heading"`
+	MotionStatus  string    `json:"motion_status"`
+	DistanceM     float64   `json:"distance_m"`
+	FixAgeSeconds int64     `json:"fix_age_seconds"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// LiveLocationsQuery selects agents for the bulk live-location endpoint. It
+// is posted as a body when the agent list is too long for a query string.
+type LiveLocationsQuery struct {
+	AgentIDs    []string `json:"agent_ids"`
+	Status      string   `json:"status"`
+	VehicleType string   `json:"vehicle_type"`
+	MaxAgeSecs  int      `json:"max_age_s"`
+}
+
+type LiveLocationResponse struct {
+	AgentID       string    `json:"agent_id"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	VehicleType   string    `json:"vehicle_type"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	Speed         float64   `json:"speed"`
+	Heading       float64   `json:"heading"`
+	Accuracy      float64   `json:"accuracy"`
+	MotionStatus  string    `json:"motion_status"`
+	Quality       string    `json:"quality"`
+	FixAgeSeconds int64     `json:"fix_age_seconds"`
+	Timestamp     time.Time `json:"timestamp"`
 }
\ No newline at end of file
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+const (
+	MotionStopped = "stopped"
+	MotionIdle    = "idle"
+	MotionMoving  = "moving"
+	MotionUnknown = "unknown"
+)
+
+// MotionState is an agent's smoothed motion state. A different state only
+// takes over once it has been observed for the vehicle's minimum duration;
+// until then it is kept as the candidate.
+type MotionState struct {
+	AgentID        string    `gorm:"primaryKey"`
+	TenantID       string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	State          string    `gorm:"type:varchar(20);not null"`
+	Since          time.Time `gorm:"not null"`
+	Candidate      string    `gorm:"type:varchar(20)"`
+	CandidateSince *time.Time
+	SpeedKmh       float64   `gorm:"type:decimal(6,2)"` // Latest smoothed speed estimate
+	LastSeenAt     time.Time `gorm:"not null"`
+}
+
+func (MotionState) TableName() string {
+	return "motion_states"
+}
+
+// MotionEvent records a change of an agent's smoothed motion state.
+type MotionEvent struct {
+	ID           uint      `gorm:"primaryKey"`
+	TenantID     string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID      string    `gorm:"index:idx_motion_events_agent_time;not null"`
+	FromState    string    `gorm:"type:varchar(20);not null"`
+	ToState      string    `gorm:"type:varchar(20);not null"`
+	Latitude     float64   `gorm:"type:decimal(10,8);not null"`
+	Longitude    float64   `gorm:"type:decimal(11,8);not null"`
+	DurationSecs int       `gorm:"default:0"` // Time spent in FromState
+	Timestamp    time.Time `gorm:"index:idx_motion_events_agent_time;not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+func (MotionEvent) TableName() string {
+	return "motion_events"
+}
+
+type MotionEventResponse struct {
+	ID           uint      `json:"id"`
+	AgentID      string    `json:"agent_id"`
+	FromState    string    `json:"from_state"`
+	ToState      string    `json:"to_state"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	DurationSecs int       `json:"duration_seconds"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type MotionStateResponse struct {
+	AgentID        string     `json:"agent_id"`
+	State          string     `json:"state"`
+	Since          time.Time  `json:"since"`
+	Candidate      string     `json:"candidate,omitempty"`
+	CandidateSince *time.Time `json:"candidate_since,omitempty"`
+	SpeedKmh       float64    `json:"speed_kmh"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+}
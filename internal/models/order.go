@@ -0,0 +1,124 @@
+package models
+
+import "time"
+
+const (
+	OrderCreated   = "created"
+	OrderAssigned  = "assigned"
+	OrderPickedUp  = "picked_up"
+	OrderInTransit = "in_transit"
+	OrderDelivered = "delivered"
+	OrderFailed    = "failed"
+	OrderCancelled = "cancelled"
+)
+
+// orderTransitions lists the statuses each order status may move to.
+// Delivered, failed and cancelled are terminal.
+var orderTransitions = map[string][]string{
+	OrderCreated:   {OrderAssigned, OrderCancelled},
+	OrderAssigned:  {OrderPickedUp, OrderCreated, OrderCancelled},
+	OrderPickedUp:  {OrderInTransit, OrderFailed},
+	OrderInTransit: {OrderDelivered, OrderFailed},
+}
+
+func IsValidOrderStatus(status string) bool {
+	switch status {
+	case OrderCreated, OrderAssigned, OrderPickedUp, OrderInTransit,
+		OrderDelivered, OrderFailed, OrderCancelled:
+		return true
+	}
+	return false
+}
+
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	ID              uint    `gorm:"primaryKey"`
+	TenantID        string  `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID         *string `gorm:"index"` // Assigned agent, empty until assigned
+	CustomerName    string  `gorm:"not null"`
+	CustomerPhone   string  `gorm:"not null"`
+	PickupLatitude  float64 `gorm:"type:decimal(10,8);not null"`
+	PickupLongitude float64 `gorm:"type:decimal(11,8);not null"`
+	PickupAddress   string
+	DropLatitude    float64 `gorm:"type:decimal(10,8);not null"`
+	DropLongitude   float64 `gorm:"type:decimal(11,8);not null"`
+	DropAddress     string
+	Notes           string
+	Status          string `gorm:"type:varchar(20);index;default:'created'"` // created, assigned, picked_up, in_transit, delivered, failed, cancelled
+	FailureReason   string
+	AssignedAt      *time.Time
+	PickedUpAt      *time.Time
+	InTransitAt     *time.Time
+	DeliveredAt     *time.Time
+	FailedAt        *time.Time
+	CancelledAt     *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
+
+func (o Order) AgentIDValue() string {
+	if o.AgentID == nil {
+		return ""
+	}
+	return *o.AgentID
+}
+
+type OrderRequest struct {
+	CustomerName    string  `json:"customer_name"`
+	CustomerPhone   string  `json:"customer_phone"`
+	PickupLatitude  float64 `json:"pickup_latitude"`
+	PickupLongitude float64 `json:"pickup_longitude"`
+	PickupAddress   string  `json:"pickup_address"`
+	DropLatitude    float64 `json:"drop_latitude"`
+	DropLongitude   float64 `json:"drop_longitude"`
+	DropAddress     string  `json:"drop_address"`
+	Notes           string  `json:"notes"`
+}
+
+type OrderStatusUpdate struct {
+	Status  string `json:"status"`
+	AgentID string `json:"agent_id"` // Required when moving to assigned
+	Reason  string `json:"reason"`   // Recorded when moving to failed or cancelled
+}
+
+type OrderResponse struct {
+	ID              uint       `json:"id"`
+	AgentID         string     `json:"agent_id,omitempty"`
+	CustomerName    string     `json:"customer_name"`
+	CustomerPhone   string     `json:"customer_phone"`
+	PickupLatitude  float64    `json:"pickup_latitude"`
+	PickupLongitude float64    `json:"pickup_longitude"`
+	PickupAddress   string     `json:"pickup_address"`
+	DropLatitude    float64    `json:"drop_latitude"`
+	DropLongitude   float64    `json:"drop_longitude"`
+	DropAddress     string     `json:"drop_address"`
+	Notes           string     `json:"notes"`
+	Status          string     `json:"status"`
+	FailureReason   string     `json:"failure_reason,omitempty"`
+	AssignedAt      *time.Time `json:"assigned_at,omitempty"`
+	PickedUpAt      *time.Time `json:"picked_up_at,omitempty"`
+	InTransitAt     *time.Time `json:"in_transit_at,omitempty"`
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
+	FailedAt        *time.Time `json:"failed_at,omitempty"`
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type DispatchRequest struct {
+	VehicleTypes     []string `json:"vehicle_types"`       // Allowed vehicle types, most preferred first
+	MaxRadiusM       float64  `json:"max_radius_m"`        // Optional search radius around pickup
+	MaxFixAgeSeconds int      `json:"max_fix_age_seconds"` // Ignore agents with older locations
+}
@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestCanTransitionOrder(t *testing.T) {
+	statuses := []string{
+		OrderCreated, OrderAssigned, OrderPickedUp, OrderInTransit,
+		OrderDelivered, OrderFailed, OrderCancelled,
+	}
+
+	allowed := map[[2]string]bool{
+		{OrderCreated, OrderAssigned}:    true,
+		{OrderCreated, OrderCancelled}:   true,
+		{OrderAssigned, OrderPickedUp}:   true,
+		{OrderAssigned, OrderCreated}:    true, // Unassigned
+		{OrderAssigned, OrderCancelled}:  true,
+		{OrderPickedUp, OrderInTransit}:  true,
+		{OrderPickedUp, OrderFailed}:     true,
+		{OrderInTransit, OrderDelivered}: true,
+		{OrderInTransit, OrderFailed}:    true,
+	}
+
+	// Every pair not listed above, including staying put and leaving a
+	// terminal status, must be rejected.
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			t.Run(from+" to "+to, func(t *testing.T) {
+				if got := CanTransitionOrder(from, to); got != want {
+					t.Errorf("CanTransitionOrder(%q, %q) = %v, want %v", from, to, got, want)
+				}
+			})
+		}
+	}
+
+	for _, tt := range []struct{ from, to string }{
+		{"", OrderAssigned},
+		{OrderCreated, ""},
+		{"unknown", OrderCreated},
+		{OrderCreated, "unknown"},
+	} {
+		if CanTransitionOrder(tt.from, tt.to) {
+			t.Errorf("CanTransitionOrder(%q, %q) = true, want false", tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type PlaybackPoint struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Speed     float64   `json:"speed"`
+	Heading   float64   `json:"heading"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PlaybackSegment is a stretch of the track without reporting gaps.
+type PlaybackSegment struct {
+	StartedAt    time.Time       `json:"started_at"`
+	EndedAt      time.Time       `json:"ended_at"`
+	DurationSecs int64           `json:"duration_seconds"`
+	DistanceKm   float64         `json:"distance_km"`
+	RawPoints    int             `json:"raw_points"`
+	Points       []PlaybackPoint `json:"points"`
+}
+
+// Playback is an agent's route over a window, oldest first, split into
+// segments wherever the agent went silent for longer than GapSecs.
+type Playback struct {
+	AgentID        string            `json:"agent_id"`
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	ToleranceM     float64           `json:"tolerance_m"`
+	BucketSecs     int               `json:"bucket_seconds"`
+	GapSecs        int               `json:"gap_seconds"`
+	RawPoints      int               `json:"raw_points"`
+	ReturnedPoints int               `json:"returned_points"`
+	DistanceKm     float64           `json:"distance_km"`
+	Segments       []PlaybackSegment `json:"segments"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+const (
+	MinRatingScore = 1
+	MaxRatingScore = 5
+)
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return jsonValue(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// Rating is a customer's score for a delivered order. Each order can be
+// rated once.
+type Rating struct {
+	ID          uint       `gorm:"primaryKey"`
+	TenantID    string     `gorm:"type:varchar(64);not null;default:'default';index"`
+	OrderID     uint       `gorm:"uniqueIndex;not null"`
+	AgentID     string     `gorm:"index:idx_ratings_agent_time;not null"`
+	Score       int        `gorm:"not null;check:score BETWEEN 1 AND 5"`
+	Comment     string     `gorm:"type:text"`
+	Tags        StringList `gorm:"type:jsonb;not null;default:'[]'"`
+	SubmittedBy string     `gorm:"type:varchar(120)"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime;index:idx_ratings_agent_time"`
+}
+
+func (Rating) TableName() string {
+	return "ratings"
+}
+
+type RatingRequest struct {
+	Score   int      `json:"score"`
+	Comment string   `json:"comment"`
+	Tags    []string `json:"tags"`
+}
+
+type RatingResponse struct {
+	ID          uint      `json:"id"`
+	OrderID     uint      `json:"order_id"`
+	AgentID     string    `json:"agent_id"`
+	Score       int       `json:"score"`
+	Comment     string    `json:"comment,omitempty"`
+	Tags        []string  `json:"tags"`
+	SubmittedBy string    `json:"submitted_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RatingSummary aggregates an agent's ratings.
+type RatingSummary struct {
+	AgentID       string           `json:"agent_id"`
+	Average       float64          `json:"average"`
+	Count         int64            `json:"count"`
+	RecentAverage float64          `json:"recent_average"` // Over the rolling window
+	RecentCount   int64            `json:"recent_count"`
+	RecentDays    int              `json:"recent_days"`
+	Distribution  map[int]int64    `json:"distribution"` // Score -> number of ratings
+	Tags          map[string]int64 `json:"tags"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+const (
+	ShiftScheduled = "scheduled"
+	ShiftActive    = "active" // Clocked in
+	ShiftCompleted = "completed"
+	ShiftCancelled = "cancelled"
+
+	// Reported for scheduled shifts that ended without a clock-in; never stored.
+	ShiftMissed = "missed"
+)
+
+// Shift is a planned block of working hours for an agent together with what
+// actually happened: when the agent clocked in and out.
+type Shift struct {
+	ID             uint      `gorm:"primaryKey"`
+	TenantID       string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID        string    `gorm:"index:idx_shifts_agent_start;not null"`
+	StartsAt       time.Time `gorm:"index:idx_shifts_agent_start;not null"`
+	EndsAt         time.Time `gorm:"index;not null"`
+	Status         string    `gorm:"type:varchar(20);not null;default:'scheduled';index"`
+	Notes          string    `gorm:"type:varchar(255)"`
+	ClockInAt      *time.Time
+	ClockOutAt     *time.Time
+	LateSecs       int       `gorm:"default:0"`     // Clock-in after the planned start
+	EarlyLeaveSecs int       `gorm:"default:0"`     // Clock-out before the planned end
+	MissedClockOut bool      `gorm:"default:false"` // Closed at the planned end because the agent never clocked out
+	CreatedBy      string    `gorm:"type:varchar(120)"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Shift) TableName() string {
+	return "shifts"
+}
+
+// ClockedInAt reports whether the agent was clocked in for the shift at t.
+func (s Shift) ClockedInAt(t time.Time) bool {
+	if s.ClockInAt == nil || s.ClockInAt.After(t) {
+		return false
+	}
+	return s.ClockOutAt == nil || s.ClockOutAt.After(t)
+}
+
+type ShiftRequest struct {
+	AgentID  string    `json:"agent_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Notes    string    `json:"notes"`
+}
+
+type ShiftResponse struct {
+	ID             uint       `json:"id"`
+	AgentID        string     `json:"agent_id"`
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         time.Time  `json:"ends_at"`
+	Status         string     `json:"status"` // scheduled, active, completed, cancelled or missed
+	Notes          string     `json:"notes,omitempty"`
+	ClockInAt      *time.Time `json:"clock_in_at,omitempty"`
+	ClockOutAt     *time.Time `json:"clock_out_at,omitempty"`
+	Late           bool       `json:"late"`
+	LateSecs       int        `json:"late_seconds"`
+	LeftEarly      bool       `json:"left_early"`
+	EarlyLeaveSecs int        `json:"early_leave_seconds"`
+	MissedClockOut bool       `json:"missed_clock_out"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Where an agent stands at a point in time relative to its shifts.
+const (
+	CoverageOnShift        = "on_shift"         // Scheduled and online
+	CoverageAbsent         = "absent"           // Scheduled but offline
+	CoverageOffShiftOnline = "off_shift_online" // Online without a scheduled shift
+)
+
+type ShiftCoverageEntry struct {
+	AgentID   string     `json:"agent_id"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"` // Agent status at the time
+	Coverage  string     `json:"coverage"`
+	ShiftID   *uint      `json:"shift_id,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	ClockedIn bool       `json:"clocked_in"`
+	Late      bool       `json:"late"` // Past the grace period without a clock-in, or clocked in late
+}
+
+// ShiftCoverage compares who was scheduled with who was online at a time.
+type ShiftCoverage struct {
+	At             time.Time            `json:"at"`
+	Scheduled      int                  `json:"scheduled"`
+	OnShift        int                  `json:"on_shift"`
+	Absent         int                  `json:"absent"`
+	OffShiftOnline int                  `json:"off_shift_online"`
+	Agents         []ShiftCoverageEntry `json:"agents"`
+}
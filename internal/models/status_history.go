@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// What caused an agent's status to change.
+const (
+	StatusSourceOperator = "operator" // An operator changed it through the API
+	StatusSourceAgent    = "agent"    // The agent's own device changed it
+	StatusSourceDispatch = "dispatch" // Claimed by automatic dispatch
+	StatusSourceOrder    = "order"    // Claimed or released by an order status change
+	StatusSourceSystem   = "system"   // Background jobs and deactivation
+)
+
+// StatusTrigger describes who or what is changing an agent's status.
+type StatusTrigger struct {
+	Source string
+	Actor  string // e.g. user email, agent ID or order ID
+	Reason string
+}
+
+// AgentStatusChange is one recorded status transition of an agent.
+type AgentStatusChange struct {
+	ID         uint      `gorm:"primaryKey"`
+	TenantID   string    `gorm:"type:varchar(64);not null;default:'default';index"`
+	AgentID    string    `gorm:"index:idx_status_changes_agent_time;not null"`
+	FromStatus string    `gorm:"type:varchar(20)"`
+	ToStatus   string    `gorm:"type:varchar(20);not null"`
+	Source     string    `gorm:"type:varchar(20);not null"`
+	Actor      string    `gorm:"type:varchar(120)"`
+	Reason     string    `gorm:"type:varchar(255)"`
+	Timestamp  time.Time `gorm:"index:idx_status_changes_agent_time;not null"`
+}
+
+func (AgentStatusChange) TableName() string {
+	return "agent_status_changes"
+}
+
+type AgentStatusChangeResponse struct {
+	ID         uint      `json:"id"`
+	AgentID    string    `json:"agent_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Source     string    `json:"source"`
+	Actor      string    `json:"actor,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// StatusTimeReport is the time an agent spent in each status over a range.
+type StatusTimeReport struct {
+	AgentID       string             `json:"agent_id"`
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	TrackedSecs   float64            `json:"tracked_seconds"` // Part of the range the agent existed for
+	StatusSecs    map[string]float64 `json:"status_seconds"`
+	StatusPercent map[string]float64 `json:"status_percent"`
+	Transitions   int                `json:"transitions"`
+}
+
+type FleetStatusTimeReport struct {
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	AgentCount    int                `json:"agent_count"`
+	TrackedSecs   float64            `json:"tracked_seconds"`
+	StatusSecs    map[string]float64 `json:"status_seconds"`
+	StatusPercent map[string]float64 `json:"status_percent"`
+	Transitions   int                `json:"transitions"`
+	Agents        []StatusTimeReport `json:"agents"`
+}
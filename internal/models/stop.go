@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Stop is a place where an agent stayed within a small radius for a while.
+type Stop struct {
+	Latitude     float64   `json:"latitude"` // Centroid of the fixes
+	Longitude    float64   `json:"longitude"`
+	ArrivedAt    time.Time `json:"arrived_at"`
+	DepartedAt   time.Time `json:"departed_at"`
+	DurationSecs int64     `json:"duration_seconds"`
+	PointCount   int       `json:"point_count"`
+	SpreadM      float64   `json:"spread_m"` // Farthest fix from the centroid
+	Ongoing      bool      `json:"ongoing"`  // Still there at the end of the window
+}
+
+type StopReport struct {
+	AgentID         string    `json:"agent_id"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	RadiusM         float64   `json:"radius_m"`
+	MinDurationSecs int64     `json:"min_duration_seconds"`
+	Count           int       `json:"count"`
+	TotalDwellSecs  int64     `json:"total_dwell_seconds"`
+	LongestSecs     int64     `json:"longest_seconds"`
+	Stops           []Stop    `json:"stops"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DefaultTenantID is the fleet that data recorded before multi-tenancy
+// belongs to. Its admins also manage the other tenants.
+const DefaultTenantID = "default"
+
+// Tenant is a client fleet. Agents, locations, orders, geofences and users
+// all belong to exactly one tenant and are never visible to another.
+type Tenant struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"` // e.g. acme-logistics
+	Name      string    `gorm:"not null"`
+	IsActive  bool      `gorm:"default:true"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// TenantRequest creates a tenant together with its first admin user.
+type TenantRequest struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	AdminEmail    string `json:"admin_email"`
+	AdminName     string `json:"admin_name"`
+	AdminPassword string `json:"admin_password"`
+}
+
+type TenantResponse struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	IsActive  bool          `json:"is_active"`
+	CreatedAt time.Time     `json:"created_at"`
+	Admin     *UserResponse `json:"admin,omitempty"`
+}
@@ -0,0 +1,221 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventLocation = "location"
+	EventStatus   = "status"
+	EventGeofence = "geofence"
+	EventMotion   = "motion"
+)
+
+type Event struct {
+	ID        uint64      `json:"id"`
+	TenantID  string      `json:"-"`
+	Type      string      `json:"type"`
+	AgentID   string      `json:"agent_id"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Subscriber receives its tenant's events matching its agent and type
+// filters. An empty agent or type filter matches everything.
+type Subscriber struct {
+	events   chan Event
+	tenantID string
+
+	mu     sync.RWMutex
+	agents map[string]bool
+	types  map[string]bool
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) SetAgents(agentIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents = toSet(agentIDs)
+}
+
+func (s *Subscriber) matches(event Event) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if event.TenantID != s.tenantID {
+		return false
+	}
+	if len(s.types) > 0 && !s.types[event.Type] {
+		return false
+	}
+	if len(s.agents) > 0 && !s.agents[event.AgentID] {
+		return false
+	}
+	return true
+}
+
+// Hub is an in-process pub/sub for tracking events. Every subscriber owns a
+// bounded buffer; a subscriber that falls behind is dropped so one slow
+// client can never block ingestion. Each tenant numbers its own events and
+// keeps its last replaySize of them, so reconnecting clients can resume from
+// the ID they last saw without a busy tenant pushing out a quiet one's
+// history.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	bufferSize  int
+
+	tenants    map[string]*replayBuffer
+	replaySize int
+}
+
+// replayBuffer is a ring of a tenant's latest events. The event with ID n
+// sits in slot (n-1) % len(events).
+type replayBuffer struct {
+	seq    uint64
+	events []Event
+}
+
+// Replay is what a resuming subscriber missed.
+type Replay struct {
+	Events []Event
+
+	// Reset is set when some events after the requested ID are no longer
+	// buffered. The client must refetch current state instead of relying on
+	// the stream to fill the gap; Events is empty then.
+	Reset bool
+
+	// LastID is the ID of the tenant's latest event when the subscriber
+	// registered.
+	LastID uint64
+}
+
+func NewHub(bufferSize, replaySize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = 64
+	}
+	if replaySize < 0 {
+		replaySize = 0
+	}
+	return &Hub{
+		subscribers: make(map[*Subscriber]struct{}),
+		bufferSize:  bufferSize,
+		tenants:     make(map[string]*replayBuffer),
+		replaySize:  replaySize,
+	}
+}
+
+func (h *Hub) Subscribe(tenantID string, agentIDs []string, types []string) *Subscriber {
+	sub, _ := h.SubscribeFrom(0, tenantID, agentIDs, types)
+	return sub
+}
+
+// SubscribeFrom registers a subscriber and returns the tenant's buffered
+// events published after lastID that match its filters. Registration and
+// replay happen under the same lock, so nothing published in between is
+// lost. A lastID of 0 skips replay. A lastID older than the buffer, or ahead
+// of the tenant's sequence (e.g. issued before a restart), asks the client
+// to reset.
+func (h *Hub) SubscribeFrom(lastID uint64, tenantID string, agentIDs []string, types []string) (*Subscriber, Replay) {
+	sub := &Subscriber{
+		events:   make(chan Event, h.bufferSize),
+		tenantID: tenantID,
+		agents:   toSet(agentIDs),
+		types:    toSet(types),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay Replay
+	buffer := h.tenants[tenantID]
+	if buffer != nil {
+		replay.LastID = buffer.seq
+	}
+
+	if lastID > 0 && lastID != replay.LastID {
+		if lastID > replay.LastID || replay.LastID-lastID > uint64(h.replaySize) {
+			replay.Reset = true
+		} else {
+			size := uint64(len(buffer.events))
+			for id := lastID + 1; id <= buffer.seq; id++ {
+				event := buffer.events[(id-1)%size]
+				if sub.matches(event) {
+					replay.Events = append(replay.Events, event)
+				}
+			}
+		}
+	}
+
+	h.subscribers[sub] = struct{}{}
+	return sub, replay
+}
+
+// Unsubscribe removes the subscriber and closes its channel. It is safe to
+// call more than once.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+func (h *Hub) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	var lagging []*Subscriber
+
+	h.mu.Lock()
+	buffer := h.tenants[event.TenantID]
+	if buffer == nil {
+		buffer = &replayBuffer{events: make([]Event, h.replaySize)}
+		h.tenants[event.TenantID] = buffer
+	}
+	buffer.seq++
+	event.ID = buffer.seq
+	if h.replaySize > 0 {
+		buffer.events[(buffer.seq-1)%uint64(h.replaySize)] = event
+	}
+
+	for sub := range h.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			lagging = append(lagging, sub)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, sub := range lagging {
+		h.Unsubscribe(sub)
+	}
+}
+
+func (h *Hub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,125 @@
+package realtime
+
+import "testing"
+
+func TestHubSubscribeFromReplay(t *testing.T) {
+	publish := func(h *Hub, tenantID string, n int) {
+		for i := 0; i < n; i++ {
+			h.Publish(Event{TenantID: tenantID, Type: EventLocation, AgentID: "agent-1"})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(h *Hub)
+		tenantID  string
+		lastID    uint64
+		wantIDs   []uint64
+		wantReset bool
+		wantLast  uint64
+	}{
+		{
+			name:     "no last id skips replay",
+			setup:    func(h *Hub) { publish(h, "t1", 3) },
+			tenantID: "t1",
+			lastID:   0,
+			wantLast: 3,
+		},
+		{
+			name:     "replays events after last id",
+			setup:    func(h *Hub) { publish(h, "t1", 3) },
+			tenantID: "t1",
+			lastID:   1,
+			wantIDs:  []uint64{2, 3},
+			wantLast: 3,
+		},
+		{
+			name:     "caught up",
+			setup:    func(h *Hub) { publish(h, "t1", 3) },
+			tenantID: "t1",
+			lastID:   3,
+			wantLast: 3,
+		},
+		{
+			name:     "ring wrapped but last id still buffered",
+			setup:    func(h *Hub) { publish(h, "t1", 6) },
+			tenantID: "t1",
+			lastID:   2,
+			wantIDs:  []uint64{3, 4, 5, 6},
+			wantLast: 6,
+		},
+		{
+			name:      "last id older than the buffer",
+			setup:     func(h *Hub) { publish(h, "t1", 6) },
+			tenantID:  "t1",
+			lastID:    1,
+			wantReset: true,
+			wantLast:  6,
+		},
+		{
+			name:      "last id ahead of the hub",
+			setup:     func(h *Hub) { publish(h, "t1", 2) },
+			tenantID:  "t1",
+			lastID:    10,
+			wantReset: true,
+			wantLast:  2,
+		},
+		{
+			name: "busy tenant keeps a quiet tenant's history",
+			setup: func(h *Hub) {
+				publish(h, "quiet", 2)
+				publish(h, "busy", 50)
+			},
+			tenantID: "quiet",
+			lastID:   1,
+			wantIDs:  []uint64{2},
+			wantLast: 2,
+		},
+		{
+			name:      "unknown tenant with a last id",
+			setup:     func(h *Hub) { publish(h, "t1", 2) },
+			tenantID:  "t2",
+			lastID:    1,
+			wantReset: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(8, 4)
+			tt.setup(h)
+
+			sub, replay := h.SubscribeFrom(tt.lastID, tt.tenantID, nil, nil)
+			defer h.Unsubscribe(sub)
+
+			if replay.Reset != tt.wantReset {
+				t.Errorf("reset = %v, want %v", replay.Reset, tt.wantReset)
+			}
+			if replay.LastID != tt.wantLast {
+				t.Errorf("last id = %d, want %d", replay.LastID, tt.wantLast)
+			}
+			if len(replay.Events) != len(tt.wantIDs) {
+				t.Fatalf("replayed %d events, want %d", len(replay.Events), len(tt.wantIDs))
+			}
+			for i, event := range replay.Events {
+				if event.ID != tt.wantIDs[i] || event.TenantID != tt.tenantID {
+					t.Errorf("event %d = %s/%d, want %s/%d", i, event.TenantID, event.ID, tt.tenantID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHubReplayFiltersByType(t *testing.T) {
+	h := NewHub(8, 4)
+	h.Publish(Event{TenantID: "t1", Type: EventLocation})
+	h.Publish(Event{TenantID: "t1", Type: EventStatus})
+	h.Publish(Event{TenantID: "t1", Type: EventLocation})
+
+	sub, replay := h.SubscribeFrom(1, "t1", nil, []string{EventLocation})
+	defer h.Unsubscribe(sub)
+
+	if len(replay.Events) != 1 || replay.Events[0].ID != 3 {
+		t.Fatalf("replayed %+v, want only event 3", replay.Events)
+	}
+}
@@ -2,13 +2,23 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/Naitik-ag/fleetintel-backend/internal/models"
 )
 
+var (
+	// ErrAgentIDUnavailable deliberately does not say whether the ID is
+	// taken in this tenant or another one.
+	ErrAgentIDUnavailable = errors.New("agent ID is not available")
+	ErrAgentPhoneExists   = errors.New("agent with this phone number already exists")
+)
+
 type AgentRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	tenantID string
 }
 
 func NewAgentRepository(db *gorm.DB) *AgentRepository {
@@ -17,28 +27,53 @@ func NewAgentRepository(db *gorm.DB) *AgentRepository {
 	}
 }
 
+// WithTx returns a copy of the repository that runs on the given transaction.
+func (r *AgentRepository) WithTx(tx *gorm.DB) *AgentRepository {
+	return &AgentRepository{
+		db:       tx,
+		tenantID: r.tenantID,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's agents.
+func (r *AgentRepository) ForTenant(tenantID string) *AgentRepository {
+	return &AgentRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *AgentRepository) scoped() *gorm.DB {
+	return scopeTenant(r.db, "delivery_agents", r.tenantID)
+}
+
 func (r *AgentRepository) Create(agent *models.DeliveryAgent) error {
+	if ownsTenant(r.tenantID) {
+		agent.TenantID = r.tenantID
+	}
+
 	var existing models.DeliveryAgent
 	result := r.db.Where("id = ?", agent.ID).First(&existing)
 	
 	if result.Error == nil {
-		return errors.New("agent with this ID already exists")
+		return ErrAgentIDUnavailable
 	}
 	
 	if result.Error != gorm.ErrRecordNotFound {
 		return result.Error
 	}
 	
-	result = r.db.Where("phone = ?", agent.Phone).First(&existing)
+	result = r.db.Where("tenant_id = ? AND phone = ?", agent.TenantID, agent.Phone).First(&existing)
 	
 	if result.Error == nil {
-		return errors.New("agent with this phone number already exists")
+		return ErrAgentPhoneExists
 	}
 	
 	if result.Error != gorm.ErrRecordNotFound {
 		return result.Error
 	}
-	
+
 	result = r.db.Create(agent)
 	return result.Error
 }
@@ -46,7 +81,7 @@ func (r *AgentRepository) Create(agent *models.DeliveryAgent) error {
 func (r *AgentRepository) FindByID(agentID string) (*models.DeliveryAgent, error) {
 	var agent models.DeliveryAgent
 	
-	result := r.db.Where("id = ?", agentID).First(&agent)
+	result := r.scoped().Where("id = ?", agentID).First(&agent)
 	
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -62,7 +97,7 @@ func (r *AgentRepository) FindAll(limit, offset int, status string) ([]models.De
 	var agents []models.DeliveryAgent
 	var totalCount int64
 	
-	query := r.db.Model(&models.DeliveryAgent{})
+	query := r.scoped().Model(&models.DeliveryAgent{})
 	
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -86,7 +121,7 @@ func (r *AgentRepository) FindAll(limit, offset int, status string) ([]models.De
 }
 
 func (r *AgentRepository) Update(agentID string, updates map[string]interface{}) error {
-	result := r.db.Model(&models.DeliveryAgent{}).
+	result := r.scoped().Model(&models.DeliveryAgent{}).
 		Where("id = ?", agentID).
 		Updates(updates)
 	
@@ -101,7 +136,9 @@ func (r *AgentRepository) Update(agentID string, updates map[string]interface{})
 	return nil
 }
 
-func (r *AgentRepository) UpdateStatus(agentID string, status string) error {
+// UpdateStatus sets the agent's status and records the transition with
+// its trigger. It returns the status the agent had before.
+func (r *AgentRepository) UpdateStatus(agentID string, status string, trigger models.StatusTrigger) (string, error) {
 	validStatuses := map[string]bool{
 		"available": true,
 		"busy":      true,
@@ -109,26 +146,120 @@ func (r *AgentRepository) UpdateStatus(agentID string, status string) error {
 	}
 	
 	if !validStatuses[status] {
-		return errors.New("invalid status. Must be: available, busy, or offline")
+		return "", errors.New("invalid status. Must be: available, busy, or offline")
 	}
 	
-	result := r.db.Model(&models.DeliveryAgent{}).
-		Where("id = ?", agentID).
-		Update("status", status)
+	var previous string
+	
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var agent models.DeliveryAgent
+		
+		result := scopeTenant(tx, "delivery_agents", r.tenantID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", agentID).
+			First(&agent)
+		
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				return errors.New("agent not found")
+			}
+			return result.Error
+		}
+		
+		previous = agent.Status
+		if previous == status {
+			return nil
+		}
+		
+		if err := tx.Model(&agent).Update("status", status).Error; err != nil {
+			return err
+		}
+		
+		return recordStatusChange(tx, agent.TenantID, agentID, previous, status, trigger)
+	})
 	
-	if result.Error != nil {
-		return result.Error
-	}
+	return previous, err
+}
+
+func recordStatusChange(tx *gorm.DB, tenantID, agentID, from, to string, trigger models.StatusTrigger) error {
+	return tx.Create(&models.AgentStatusChange{
+		TenantID:   tenantID,
+		AgentID:    agentID,
+		FromStatus: from,
+		ToStatus:   to,
+		Source:     trigger.Source,
+		Actor:      trigger.Actor,
+		Reason:     trigger.Reason,
+		Timestamp:  time.Now(),
+	}).Error
+}
+
+// switchStatus moves the agent from one status to another only if it is
+// currently in the from status, and records the transition. It reports
+// whether the agent was switched.
+func (r *AgentRepository) switchStatus(agentID, from, to string, activeOnly bool, trigger models.StatusTrigger) (bool, error) {
+	switched := false
+	
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var agent models.DeliveryAgent
+		
+		query := scopeTenant(tx, "delivery_agents", r.tenantID).
+			Model(&agent).
+			Clauses(clause.Returning{}).
+			Where("id = ? AND status = ?", agentID, from)
+		if activeOnly {
+			query = query.Where("is_active = ?", true)
+		}
+		
+		result := query.Update("status", to)
+		if result.Error != nil {
+			return result.Error
+		}
+		
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		
+		switched = true
+		return recordStatusChange(tx, agent.TenantID, agentID, from, to, trigger)
+	})
 	
-	if result.RowsAffected == 0 {
-		return errors.New("agent not found")
+	return switched, err
+}
+
+// FindAvailable returns active agents currently marked available, optionally
+// restricted to the given vehicle types.
+func (r *AgentRepository) FindAvailable(vehicleTypes []string) ([]models.DeliveryAgent, error) {
+	var agents []models.DeliveryAgent
+
+	query := r.scoped().Where("status = ? AND is_active = ?", "available", true)
+
+	if len(vehicleTypes) > 0 {
+		query = query.Where("vehicle_type IN ?", vehicleTypes)
 	}
-	
-	return nil
+
+	err := query.Find(&agents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return agents, nil
+}
+
+// ClaimAvailable flips an available, active agent to busy. It is a single
+// conditional update, so of two concurrent claims only one succeeds.
+func (r *AgentRepository) ClaimAvailable(agentID string, trigger models.StatusTrigger) (bool, error) {
+	return r.switchStatus(agentID, "available", "busy", true, trigger)
+}
+
+// Release moves a busy agent back to available.
+func (r *AgentRepository) Release(agentID string, trigger models.StatusTrigger) error {
+	_, err := r.switchStatus(agentID, "busy", "available", false, trigger)
+	return err
 }
 
 func (r *AgentRepository) Delete(agentID string) error {
-	result := r.db.Where("id = ?", agentID).Delete(&models.DeliveryAgent{})
+	result := r.scoped().Where("id = ?", agentID).Delete(&models.DeliveryAgent{})
 	
 	if result.Error != nil {
 		return result.Error
@@ -141,22 +272,83 @@ func (r *AgentRepository) Delete(agentID string) error {
 	return nil
 }
 
-func (r *AgentRepository) SoftDelete(agentID string) error {
-	return r.Update(agentID, map[string]interface{}{
-		"is_active": false,
-		"status":    "offline",
+// SwitchStatus moves the agent to the to status only while it is still in
+// the from status, so a change made concurrently by someone else is never
+// overwritten. It reports whether the agent was switched.
+func (r *AgentRepository) SwitchStatus(agentID, from, to string, trigger models.StatusTrigger) (bool, error) {
+	return r.switchStatus(agentID, from, to, false, trigger)
+}
+
+// SilentAgent is an agent together with the last time it was heard from.
+type SilentAgent struct {
+	models.DeliveryAgent `gorm:"embedded"`
+	LastSeenAt           time.Time
+}
+
+// FindSilent returns active agents in one of the given statuses that have
+// not been heard from since cutoff. An agent is heard from when it reports
+// a location or its status changes, whichever is later.
+func (r *AgentRepository) FindSilent(statuses []string, cutoff time.Time) ([]SilentAgent, error) {
+	var agents []SilentAgent
+
+	lastSeen := "GREATEST(l.timestamp, delivery_agents.updated_at)"
+
+	err := r.scoped().Table("delivery_agents").
+		Select("delivery_agents.*, "+lastSeen+" AS last_seen_at").
+		Joins(`LEFT JOIN LATERAL (
+			SELECT timestamp FROM locations
+			WHERE locations.agent_id = delivery_agents.id
+			ORDER BY timestamp DESC LIMIT 1
+		) l ON true`).
+		Where("delivery_agents.is_active = ? AND delivery_agents.status IN ?", true, statuses).
+		Where(lastSeen+" < ?", cutoff).
+		Scan(&agents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return agents, nil
+}
+
+// SoftDelete deactivates the agent and takes it offline, recording the
+// status change if it was not offline already.
+func (r *AgentRepository) SoftDelete(agentID string, trigger models.StatusTrigger) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := r.WithTx(tx)
+
+		if _, err := repo.UpdateStatus(agentID, "offline", trigger); err != nil {
+			return err
+		}
+
+		return repo.Update(agentID, map[string]interface{}{
+			"is_active": false,
+		})
 	})
 }
 
+// ListAll returns every agent of the tenant, including deactivated ones.
+func (r *AgentRepository) ListAll() ([]models.DeliveryAgent, error) {
+	var agents []models.DeliveryAgent
+
+	if err := r.scoped().Order("id ASC").Find(&agents).Error; err != nil {
+		return nil, err
+	}
+
+	return agents, nil
+}
+
 
+// CountByStatus counts the active agents in each status.
 func (r *AgentRepository) CountByStatus() (map[string]int64, error) {
 	var results []struct {
 		Status string
 		Count  int64
 	}
 	
-	err := r.db.Model(&models.DeliveryAgent{}).
+	err := r.scoped().Model(&models.DeliveryAgent{}).
 		Select("status, COUNT(*) as count").
+		Where("is_active = ?", true).
 		Group("status").
 		Find(&results).Error
 	
@@ -172,10 +364,35 @@ func (r *AgentRepository) CountByStatus() (map[string]int64, error) {
 	return counts, nil
 }
 
+// CountByVehicleType counts the active agents per vehicle type.
+func (r *AgentRepository) CountByVehicleType() (map[string]int64, error) {
+	var results []struct {
+		VehicleType string
+		Count       int64
+	}
+
+	err := r.scoped().Model(&models.DeliveryAgent{}).
+		Select("vehicle_type, COUNT(*) as count").
+		Where("is_active = ?", true).
+		Group("vehicle_type").
+		Find(&results).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	for _, result := range results {
+		counts[result.VehicleType] = result.Count
+	}
+
+	return counts, nil
+}
+
 func (r *AgentRepository) FindByPhone(phone string) (*models.DeliveryAgent, error) {
 	var agent models.DeliveryAgent
 	
-	result := r.db.Where("phone = ?", phone).First(&agent)
+	result := r.scoped().Where("phone = ?", phone).First(&agent)
 	
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
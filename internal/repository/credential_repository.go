@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type CredentialRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewCredentialRepository(db *gorm.DB) *CredentialRepository {
+	return &CredentialRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's credentials.
+func (r *CredentialRepository) ForTenant(tenantID string) *CredentialRepository {
+	return &CredentialRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *CredentialRepository) Create(credential *models.AgentCredential) error {
+	if ownsTenant(r.tenantID) {
+		credential.TenantID = r.tenantID
+	}
+	return r.db.Create(credential).Error
+}
+
+func (r *CredentialRepository) FindByAgentID(agentID string) ([]models.AgentCredential, error) {
+	var credentials []models.AgentCredential
+
+	err := scopeTenant(r.db, "agent_credentials", r.tenantID).Where("agent_id = ?", agentID).
+		Order("created_at DESC").
+		Find(&credentials).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// FindActiveByPrefix returns the unrevoked credential with the given key
+// prefix, provided its agent and tenant are still active.
+func (r *CredentialRepository) FindActiveByPrefix(prefix string) (*models.AgentCredential, error) {
+	var credential models.AgentCredential
+
+	result := r.db.Joins("JOIN delivery_agents ON delivery_agents.id = agent_credentials.agent_id AND delivery_agents.is_active = ?", true).
+		Joins("JOIN tenants ON tenants.id = agent_credentials.tenant_id AND tenants.is_active = ?", true).
+		Where("agent_credentials.key_prefix = ? AND agent_credentials.revoked_at IS NULL", prefix).
+		First(&credential)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &credential, nil
+}
+
+func (r *CredentialRepository) TouchLastUsed(credentialID uint) error {
+	return r.db.Model(&models.AgentCredential{}).
+		Where("id = ?", credentialID).
+		Update("last_used_at", time.Now()).Error
+}
+
+func (r *CredentialRepository) Revoke(agentID string, credentialID uint) error {
+	result := scopeTenant(r.db, "agent_credentials", r.tenantID).Model(&models.AgentCredential{}).
+		Where("id = ? AND agent_id = ? AND revoked_at IS NULL", credentialID, agentID).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("credential not found")
+	}
+
+	return nil
+}
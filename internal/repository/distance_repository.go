@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type DistanceRepository struct {
+	db *gorm.DB
+}
+
+func NewDistanceRepository(db *gorm.DB) *DistanceRepository {
+	return &DistanceRepository{
+		db: db,
+	}
+}
+
+func (r *DistanceRepository) Find(agentID string) (*models.AgentDistance, error) {
+	var distance models.AgentDistance
+
+	result := r.db.Where("agent_id = ?", agentID).First(&distance)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &distance, nil
+}
+
+func (r *DistanceRepository) Save(distance *models.AgentDistance) error {
+	return r.db.Save(distance).Error
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPayRuleExists     = errors.New("a pay rule for this vehicle type already exists")
+	ErrNothingToPay      = errors.New("no unpaid earnings in the period")
+	ErrPayoutNotFound    = errors.New("payout not found")
+	ErrPayoutAlreadyPaid = errors.New("payout is already paid")
+)
+
+// EarningsRepository stores pay rules, the agents' earnings ledgers and the
+// payouts settling them.
+type EarningsRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewEarningsRepository(db *gorm.DB) *EarningsRepository {
+	return &EarningsRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository that runs on the given transaction.
+func (r *EarningsRepository) WithTx(tx *gorm.DB) *EarningsRepository {
+	return &EarningsRepository{
+		db:       tx,
+		tenantID: r.tenantID,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's pay rules, ledger entries and payouts.
+func (r *EarningsRepository) ForTenant(tenantID string) *EarningsRepository {
+	return &EarningsRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *EarningsRepository) CreateRule(rule *models.PayRule) error {
+	if ownsTenant(r.tenantID) {
+		rule.TenantID = r.tenantID
+	}
+
+	var count int64
+	err := r.db.Model(&models.PayRule{}).
+		Where("tenant_id = ? AND vehicle_type = ?", rule.TenantID, rule.VehicleType).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrPayRuleExists
+	}
+
+	return r.db.Create(rule).Error
+}
+
+func (r *EarningsRepository) FindRules() ([]models.PayRule, error) {
+	var rules []models.PayRule
+
+	err := scopeTenant(r.db, "pay_rules", r.tenantID).Order("vehicle_type ASC").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *EarningsRepository) FindRule(ruleID uint) (*models.PayRule, error) {
+	var rule models.PayRule
+
+	result := scopeTenant(r.db, "pay_rules", r.tenantID).First(&rule, ruleID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &rule, nil
+}
+
+// FindRuleFor returns the active rule for the vehicle type, falling back to
+// the tenant's default rule, or nil if neither exists.
+func (r *EarningsRepository) FindRuleFor(vehicleType string) (*models.PayRule, error) {
+	var rule models.PayRule
+
+	result := scopeTenant(r.db, "pay_rules", r.tenantID).
+		Where("is_active = ? AND vehicle_type IN ?", true, []string{vehicleType, ""}).
+		Order("vehicle_type DESC").
+		First(&rule)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &rule, nil
+}
+
+func (r *EarningsRepository) SaveRule(rule *models.PayRule) error {
+	var count int64
+	err := r.db.Model(&models.PayRule{}).
+		Where("tenant_id = ? AND vehicle_type = ? AND id <> ?", rule.TenantID, rule.VehicleType, rule.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrPayRuleExists
+	}
+
+	return r.db.Save(rule).Error
+}
+
+func (r *EarningsRepository) DeleteRule(ruleID uint) error {
+	result := scopeTenant(r.db, "pay_rules", r.tenantID).Delete(&models.PayRule{}, ruleID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("pay rule not found")
+	}
+
+	return nil
+}
+
+// CreateEntry adds an entry to the ledger. Entries whose reference is
+// already in the ledger are skipped; it reports whether the entry was added.
+func (r *EarningsRepository) CreateEntry(entry *models.EarningsEntry) (bool, error) {
+	if ownsTenant(r.tenantID) {
+		entry.TenantID = r.tenantID
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CountDeliveries counts the agent's delivery entries earned in [from, to).
+func (r *EarningsRepository) CountDeliveries(agentID string, from, to time.Time) (int64, error) {
+	var count int64
+
+	err := scopeTenant(r.db, "earnings_entries", r.tenantID).Model(&models.EarningsEntry{}).
+		Where("agent_id = ? AND type = ? AND earned_at >= ? AND earned_at < ?", agentID, models.EarningDelivery, from, to).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindEntries returns the agent's ledger entries earned in [from, to),
+// newest first. A zero limit returns all of them.
+func (r *EarningsRepository) FindEntries(agentID string, from, to time.Time, limit int) ([]models.EarningsEntry, error) {
+	var entries []models.EarningsEntry
+
+	query := scopeTenant(r.db, "earnings_entries", r.tenantID).
+		Where("agent_id = ? AND earned_at >= ? AND earned_at < ?", agentID, from, to).
+		Order("earned_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type EarningsTotal struct {
+	Type   string
+	Amount float64
+	Count  int
+}
+
+// Totals sums the agent's ledger per entry type over [from, to). A zero from
+// and to covers the whole ledger.
+func (r *EarningsRepository) Totals(agentID string, from, to time.Time) ([]EarningsTotal, error) {
+	var totals []EarningsTotal
+
+	query := scopeTenant(r.db, "earnings_entries", r.tenantID).Model(&models.EarningsEntry{}).
+		Select("type, COALESCE(SUM(amount), 0) AS amount, COUNT(*) AS count").
+		Where("agent_id = ?", agentID)
+	if !from.IsZero() || !to.IsZero() {
+		query = query.Where("earned_at >= ? AND earned_at < ?", from, to)
+	}
+
+	if err := query.Group("type").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// UnpaidTotal sums the agent's entries not yet included in a payout.
+func (r *EarningsRepository) UnpaidTotal(agentID string) (float64, error) {
+	var total float64
+
+	err := scopeTenant(r.db, "earnings_entries", r.tenantID).Model(&models.EarningsEntry{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("agent_id = ? AND payout_id IS NULL", agentID).
+		Scan(&total).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// CreatePayout settles the agent's unpaid entries earned in the payout's
+// period in one pending payout.
+func (r *EarningsRepository) CreatePayout(payout *models.Payout) error {
+	if ownsTenant(r.tenantID) {
+		payout.TenantID = r.tenantID
+	}
+	payout.Status = models.PayoutPending
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var entries []models.EarningsEntry
+
+		err := scopeTenant(tx, "earnings_entries", r.tenantID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ? AND payout_id IS NULL", payout.AgentID).
+			Where("earned_at >= ? AND earned_at < ?", payout.PeriodStart, payout.PeriodEnd).
+			Find(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			return ErrNothingToPay
+		}
+
+		ids := make([]uint, 0, len(entries))
+		amount := 0.0
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+			amount += entry.Amount
+		}
+
+		payout.Amount = math.Round(amount*100) / 100
+		payout.EntryCount = len(entries)
+		if err := tx.Create(payout).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.EarningsEntry{}).Where("id IN ?", ids).Update("payout_id", payout.ID).Error
+	})
+}
+
+func (r *EarningsRepository) FindPayouts(agentID string) ([]models.Payout, error) {
+	var payouts []models.Payout
+
+	err := scopeTenant(r.db, "payouts", r.tenantID).
+		Where("agent_id = ?", agentID).
+		Order("period_end DESC, id DESC").
+		Find(&payouts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return payouts, nil
+}
+
+// LastPayoutEnd returns the end of the agent's latest payout period, or nil
+// when the agent has never been paid out.
+func (r *EarningsRepository) LastPayoutEnd(agentID string) (*time.Time, error) {
+	var payout models.Payout
+
+	result := scopeTenant(r.db, "payouts", r.tenantID).
+		Where("agent_id = ?", agentID).
+		Order("period_end DESC").
+		Limit(1).
+		Find(&payout)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &payout.PeriodEnd, nil
+}
+
+func (r *EarningsRepository) FindPayout(agentID string, payoutID uint) (*models.Payout, error) {
+	var payout models.Payout
+
+	result := scopeTenant(r.db, "payouts", r.tenantID).
+		Where("agent_id = ?", agentID).
+		First(&payout, payoutID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &payout, nil
+}
+
+func (r *EarningsRepository) FindPayoutEntries(payoutID uint) ([]models.EarningsEntry, error) {
+	var entries []models.EarningsEntry
+
+	err := scopeTenant(r.db, "earnings_entries", r.tenantID).
+		Where("payout_id = ?", payoutID).
+		Order("earned_at ASC, id ASC").
+		Find(&entries).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// MarkPaid records that a pending payout has been paid out.
+func (r *EarningsRepository) MarkPaid(agentID string, payoutID uint) (*models.Payout, error) {
+	var payout models.Payout
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := scopeTenant(tx, "payouts", r.tenantID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ?", agentID).
+			First(&payout, payoutID)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				return ErrPayoutNotFound
+			}
+			return result.Error
+		}
+
+		if payout.Status == models.PayoutPaid {
+			return ErrPayoutAlreadyPaid
+		}
+
+		now := time.Now()
+		payout.Status = models.PayoutPaid
+		payout.PaidAt = &now
+
+		return tx.Model(&payout).Select("status", "paid_at").Updates(&payout).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &payout, nil
+}
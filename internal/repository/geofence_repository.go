@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GeofenceRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewGeofenceRepository(db *gorm.DB) *GeofenceRepository {
+	return &GeofenceRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's geofences and events.
+func (r *GeofenceRepository) ForTenant(tenantID string) *GeofenceRepository {
+	return &GeofenceRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *GeofenceRepository) Create(geofence *models.Geofence) error {
+	if ownsTenant(r.tenantID) {
+		geofence.TenantID = r.tenantID
+	}
+	return r.db.Create(geofence).Error
+}
+
+func (r *GeofenceRepository) FindByID(geofenceID uint) (*models.Geofence, error) {
+	var geofence models.Geofence
+
+	result := scopeTenant(r.db, "geofences", r.tenantID).First(&geofence, geofenceID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &geofence, nil
+}
+
+func (r *GeofenceRepository) FindAll(activeOnly bool) ([]models.Geofence, error) {
+	var geofences []models.Geofence
+
+	query := scopeTenant(r.db, "geofences", r.tenantID).Order("id ASC")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	if err := query.Find(&geofences).Error; err != nil {
+		return nil, err
+	}
+
+	return geofences, nil
+}
+
+func (r *GeofenceRepository) Save(geofence *models.Geofence) error {
+	return r.db.Save(geofence).Error
+}
+
+// Delete removes the geofence together with its tracked agent states.
+// Recorded events are kept for auditing.
+func (r *GeofenceRepository) Delete(geofenceID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := scopeTenant(tx, "geofences", r.tenantID).Delete(&models.Geofence{}, geofenceID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("geofence not found")
+		}
+
+		return tx.Where("geofence_id = ?", geofenceID).Delete(&models.GeofenceState{}).Error
+	})
+}
+
+func (r *GeofenceRepository) FindStates(agentID string) ([]models.GeofenceState, error) {
+	var states []models.GeofenceState
+
+	if err := r.db.Where("agent_id = ?", agentID).Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// SaveTransition upserts the agent's state and records any events in one
+// transaction.
+func (r *GeofenceRepository) SaveTransition(states []models.GeofenceState, events []models.GeofenceEvent) error {
+	if len(states) == 0 && len(events) == 0 {
+		return nil
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(states) > 0 {
+			err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&states).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(events) > 0 {
+			return tx.Create(&events).Error
+		}
+
+		return nil
+	})
+}
+
+type GeofenceEventFilter struct {
+	AgentID    string
+	GeofenceID uint
+	EventType  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+func (r *GeofenceRepository) FindEvents(filter GeofenceEventFilter) ([]models.GeofenceEvent, error) {
+	var events []models.GeofenceEvent
+
+	query := scopeTenant(r.db, "geofence_events", r.tenantID).Model(&models.GeofenceEvent{})
+
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+
+	if filter.GeofenceID != 0 {
+		query = query.Where("geofence_id = ?", filter.GeofenceID)
+	}
+
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp <= ?", filter.To)
+	}
+
+	err := query.Order("timestamp DESC").
+		Limit(filter.Limit).
+		Find(&events).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
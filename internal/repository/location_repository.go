@@ -1,14 +1,19 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/Naitik-ag/fleetintel-backend/internal/models"
 )
 
 type LocationRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	tenantID string
 }
 
 func NewLocationRepository(db *gorm.DB) *LocationRepository {
@@ -17,18 +22,73 @@ func NewLocationRepository(db *gorm.DB) *LocationRepository {
 	}
 }
 
-func (r *LocationRepository) Create(location *models.Location) error {
-	result := r.db.Create(location)
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's locations.
+func (r *LocationRepository) ForTenant(tenantID string) *LocationRepository {
+	return &LocationRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *LocationRepository) scoped() *gorm.DB {
+	return scopeTenant(r.db, "locations", r.tenantID)
+}
+
+// Create stores the location unless the same point is already stored, by
+// (agent_id, point_id) or by (agent_id, timestamp). On a duplicate, location
+// is replaced with the stored record and true is returned.
+func (r *LocationRepository) Create(location *models.Location) (bool, error) {
+	if ownsTenant(r.tenantID) {
+		location.TenantID = r.tenantID
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(location)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+
+	existing, err := r.FindDuplicate(*location)
+	if err != nil {
+		return false, err
+	}
+
+	if existing == nil {
+		return false, errors.New("location conflicts with a stored point that could not be found")
+	}
+
+	*location = *existing
+	return true, nil
+}
+
+func (r *LocationRepository) FindDuplicate(location models.Location) (*models.Location, error) {
+	var existing models.Location
+
+	match := r.db.Where("agent_id = ? AND timestamp = ?", location.AgentID, location.Timestamp)
+	if location.PointID != nil {
+		match = r.db.Where("agent_id = ? AND point_id = ?", location.AgentID, *location.PointID).Or(match)
+	}
+
+	result := r.scoped().Where(match).First(&existing)
+
 	if result.Error != nil {
-		return result.Error
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
 	}
-	return nil
+
+	return &existing, nil
 }
 
 func (r *LocationRepository) FindByAgentID(agentID string, limit int) ([]models.Location, error) {
 	var locations []models.Location
 	
-	result := r.db.Where("agent_id = ?", agentID).
+	result := r.scoped().Where("agent_id = ?", agentID).
 		Order("timestamp DESC").
 		Limit(limit).
 		Find(&locations)
@@ -43,7 +103,7 @@ func (r *LocationRepository) FindByAgentID(agentID string, limit int) ([]models.
 func (r *LocationRepository) FindLatestByAgentID(agentID string) (*models.Location, error) {
 	var location models.Location
 	
-	result := r.db.Where("agent_id = ?", agentID).
+	result := r.scoped().Where("agent_id = ?", agentID).
 		Order("timestamp DESC").
 		First(&location)
 	
@@ -61,7 +121,7 @@ func (r *LocationRepository) FindLatestByAgentID(agentID string) (*models.Locati
 func (r *LocationRepository) FindByAgentIDAndTimeRange(agentID string, startTime, endTime time.Time) ([]models.Location, error) {
 	var locations []models.Location
 	
-	result := r.db.Where("agent_id = ?", agentID).
+	result := r.scoped().Where("agent_id = ?", agentID).
 		Where("timestamp >= ?", startTime).
 		Where("timestamp <= ?", endTime).
 		Order("timestamp ASC").
@@ -77,7 +137,7 @@ func (r *LocationRepository) FindByAgentIDAndTimeRange(agentID string, startTime
 func (r *LocationRepository) Count(agentID string) (int64, error) {
 	var count int64
 	
-	result := r.db.Model(&models.Location{}).
+	result := r.scoped().Model(&models.Location{}).
 		Where("agent_id = ?", agentID).
 		Count(&count)
 	
@@ -86,4 +146,318 @@ func (r *LocationRepository) Count(agentID string) (int64, error) {
 	}
 	
 	return count, nil
-}
\ No newline at end of file
+}
+
+// CreateBatch stores all locations with a single multi-row insert. If a
+// concurrent writer stored one of the points first, the insert falls back to
+// one idempotent Create per point. The returned slice flags duplicates by
+// index; duplicates are replaced with the stored record.
+func (r *LocationRepository) CreateBatch(locations []models.Location) ([]bool, error) {
+	duplicates := make([]bool, len(locations))
+
+	if len(locations) == 0 {
+		return duplicates, nil
+	}
+
+	if ownsTenant(r.tenantID) {
+		for i := range locations {
+			locations[i].TenantID = r.tenantID
+		}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&locations).Error
+	})
+	if err == nil {
+		return duplicates, nil
+	}
+
+	if !isUniqueViolation(err) {
+		return nil, err
+	}
+
+	for i := range locations {
+		locations[i].ID = 0
+		duplicates[i], err = r.Create(&locations[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return duplicates, nil
+}
+
+// FindExisting returns stored locations matching any of the given points by
+// (agent_id, point_id) or by (agent_id, timestamp).
+func (r *LocationRepository) FindExisting(points []models.Location) ([]models.Location, error) {
+	var locations []models.Location
+
+	if len(points) == 0 {
+		return locations, nil
+	}
+
+	pairs := make([][]interface{}, 0, len(points))
+	var pointIDs [][]interface{}
+	for _, p := range points {
+		pairs = append(pairs, []interface{}{p.AgentID, p.Timestamp})
+		if p.PointID != nil {
+			pointIDs = append(pointIDs, []interface{}{p.AgentID, *p.PointID})
+		}
+	}
+
+	match := r.db.Where("(agent_id, timestamp) IN ?", pairs)
+	if len(pointIDs) > 0 {
+		match = match.Or("(agent_id, point_id) IN ?", pointIDs)
+	}
+
+	result := r.scoped().Where(match).Find(&locations)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return locations, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// FindLatestByAgentIDs returns the most recent location of each given agent
+// in a single query.
+func (r *LocationRepository) FindLatestByAgentIDs(agentIDs []string) ([]models.Location, error) {
+	var locations []models.Location
+
+	if len(agentIDs) == 0 {
+		return locations, nil
+	}
+
+	result := r.scoped().
+		Select("DISTINCT ON (agent_id) *").
+		Where("agent_id IN ?", agentIDs).
+		Order("agent_id, timestamp DESC").
+		Find(&locations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return locations, nil
+}
+
+type LatestLocationFilter struct {
+	AgentIDs    []string
+	Status      string
+	VehicleType string
+	Bounds      *geo.Bounds
+	Since       time.Time
+}
+
+// FindLatestWithAgents returns the latest location of every active agent
+// matching the filter, joined with the agent's profile. Bounds apply to the
+// latest fix, so an agent that has since moved away is not matched. Each
+// agent's newest point is looked up on the (agent_id, timestamp) index, so
+// the cost grows with the number of agents rather than stored locations.
+func (r *LocationRepository) FindLatestWithAgents(filter LatestLocationFilter) ([]models.AgentLocation, error) {
+	var results []models.AgentLocation
+
+	query := scopeTenant(r.db.Table("delivery_agents AS a"), "a", r.tenantID).
+		Select("l.*, a.name AS agent_name, a.status AS agent_status, a.vehicle_type, "+motionStateColumn+" AS motion_state").
+		Joins(`JOIN LATERAL (
+			SELECT * FROM locations
+			WHERE locations.agent_id = a.id
+			ORDER BY timestamp DESC LIMIT 1
+		) l ON true`).
+		Joins("LEFT JOIN motion_states ms ON ms.agent_id = a.id").
+		Where("a.is_active = ?", true)
+
+	if len(filter.AgentIDs) > 0 {
+		query = query.Where("a.id IN ?", filter.AgentIDs)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("a.status = ?", filter.Status)
+	}
+
+	if filter.VehicleType != "" {
+		query = query.Where("a.vehicle_type = ?", filter.VehicleType)
+	}
+
+	if !filter.Since.IsZero() {
+		query = query.Where("l.timestamp >= ?", filter.Since)
+	}
+
+	if filter.Bounds != nil {
+		lngMatch := r.db
+		for _, lngRange := range filter.Bounds.LngRanges() {
+			lngMatch = lngMatch.Or("l.longitude BETWEEN ? AND ?", lngRange[0], lngRange[1])
+		}
+
+		query = query.
+			Where("l.latitude BETWEEN ? AND ?", filter.Bounds.MinLat, filter.Bounds.MaxLat).
+			Where(lngMatch)
+	}
+
+	result := query.Scan(&results)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return results, nil
+}
+
+// motionStateColumn selects the agent's smoothed motion state, falling back
+// to the latest fix's own status until the state is known. It expects the
+// latest location as l and the motion state as ms.
+const motionStateColumn = "COALESCE(NULLIF(ms.state, 'unknown'), l.status)"
+
+// LatestFix is the time and motion state of an agent's latest location,
+// both nil if the agent never reported one.
+type LatestFix struct {
+	AgentID     string
+	Timestamp   *time.Time
+	MotionState *string
+}
+
+// FindLatestFixes returns the latest fix of every active agent. It walks the
+// agents and looks up each one's newest point on the (agent_id, timestamp)
+// index instead of scanning the locations table.
+func (r *LocationRepository) FindLatestFixes() ([]LatestFix, error) {
+	var fixes []LatestFix
+
+	result := scopeTenant(r.db.Table("delivery_agents"), "delivery_agents", r.tenantID).
+		Select("delivery_agents.id AS agent_id, l.timestamp, "+motionStateColumn+" AS motion_state").
+		Joins(`LEFT JOIN LATERAL (
+			SELECT timestamp, status FROM locations
+			WHERE locations.agent_id = delivery_agents.id
+			ORDER BY timestamp DESC LIMIT 1
+		) l ON true`).
+		Joins("LEFT JOIN motion_states ms ON ms.agent_id = delivery_agents.id").
+		Where("delivery_agents.is_active = ?", true).
+		Scan(&fixes)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return fixes, nil
+}
+
+// FindAfter returns up to limit locations newer than the given position, in
+// chronological order. Points sharing a timestamp are ordered by ID.
+func (r *LocationRepository) FindAfter(agentID string, after time.Time, afterID uint, limit int) ([]models.Location, error) {
+	var locations []models.Location
+
+	result := r.scoped().Where("agent_id = ?", agentID).
+		Where("(timestamp > ? OR (timestamp = ? AND id > ?))", after, after, afterID).
+		Order("timestamp ASC, id ASC").
+		Limit(limit).
+		Find(&locations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return locations, nil
+}
+
+// HasInsertedBefore reports whether a location with an ID above minID was
+// stored for the agent at or before the given position, that is, a point
+// that arrived after a reader had passed its place in the timeline.
+func (r *LocationRepository) HasInsertedBefore(agentID string, minID uint, before time.Time, beforeID uint) (bool, error) {
+	var ids []uint
+
+	result := r.scoped().Model(&models.Location{}).
+		Where("agent_id = ? AND id > ?", agentID, minID).
+		Where("(timestamp < ? OR (timestamp = ? AND id < ?))", before, before, beforeID).
+		Limit(1).
+		Pluck("id", &ids)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return len(ids) > 0, nil
+}
+
+// FindRangeAfter is FindAfter bounded by to, for paging through a time
+// range. Pass the range start with afterID 0 to begin at it inclusively.
+func (r *LocationRepository) FindRangeAfter(agentID string, after time.Time, afterID uint, to time.Time, limit int) ([]models.Location, error) {
+	var locations []models.Location
+
+	result := r.scoped().Where("agent_id = ?", agentID).
+		Where("(timestamp > ? OR (timestamp = ? AND id > ?))", after, after, afterID).
+		Where("timestamp <= ?", to).
+		Order("timestamp ASC, id ASC").
+		Limit(limit).
+		Find(&locations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return locations, nil
+}
+
+// FindWindow returns up to limit of the agent's latest locations at or
+// before upTo and not older than since, newest first. A negative limit
+// returns all of them.
+func (r *LocationRepository) FindWindow(agentID string, since, upTo time.Time, limit int) ([]models.Location, error) {
+	var locations []models.Location
+
+	result := r.scoped().Where("agent_id = ?", agentID).
+		Where("timestamp >= ?", since).
+		Where("timestamp <= ?", upTo).
+		Order("timestamp DESC, id DESC").
+		Limit(limit).
+		Find(&locations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return locations, nil
+}
+
+// FindPrevious returns the latest stored location of the agent strictly
+// before the given time, skipping suspect fixes so a new point is never
+// judged against one that was itself implausible.
+func (r *LocationRepository) FindPrevious(agentID string, before time.Time) (*models.Location, error) {
+	var location models.Location
+
+	result := r.scoped().Where("agent_id = ? AND timestamp < ?", agentID, before).
+		Where("quality <> ?", models.QualitySuspect).
+		Order("timestamp DESC").
+		First(&location)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &location, nil
+}
+
+// FindSuspectsBetween returns up to limit of the agent's latest suspect
+// locations after since and strictly before the given time, oldest first.
+func (r *LocationRepository) FindSuspectsBetween(agentID string, since, before time.Time, limit int) ([]models.Location, error) {
+	var locations []models.Location
+
+	result := r.scoped().Where("agent_id = ? AND quality = ?", agentID, models.QualitySuspect).
+		Where("timestamp > ? AND timestamp < ?", since, before).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&locations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for i, j := 0, len(locations)-1; i < j; i, j = i+1, j-1 {
+		locations[i], locations[j] = locations[j], locations[i]
+	}
+
+	return locations, nil
+}
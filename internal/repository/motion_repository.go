@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MotionRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewMotionRepository(db *gorm.DB) *MotionRepository {
+	return &MotionRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees the tenant's
+// motion states and events.
+func (r *MotionRepository) ForTenant(tenantID string) *MotionRepository {
+	return &MotionRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *MotionRepository) FindState(agentID string) (*models.MotionState, error) {
+	var state models.MotionState
+
+	result := scopeTenant(r.db, "motion_states", r.tenantID).Where("agent_id = ?", agentID).First(&state)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &state, nil
+}
+
+// SaveTransition upserts the agent's state and records any events in one
+// transaction.
+func (r *MotionRepository) SaveTransition(state *models.MotionState, events []models.MotionEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(state).Error; err != nil {
+			return err
+		}
+
+		if len(events) > 0 {
+			return tx.Create(&events).Error
+		}
+
+		return nil
+	})
+}
+
+// FindEvents returns the agent's motion events in [from, to], newest first.
+// A zero from or to leaves that end open.
+func (r *MotionRepository) FindEvents(agentID string, from, to time.Time, limit int) ([]models.MotionEvent, error) {
+	var events []models.MotionEvent
+
+	query := scopeTenant(r.db, "motion_events", r.tenantID).Where("agent_id = ?", agentID)
+
+	if !from.IsZero() {
+		query = query.Where("timestamp >= ?", from)
+	}
+
+	if !to.IsZero() {
+		query = query.Where("timestamp <= ?", to)
+	}
+
+	err := query.Order("timestamp DESC, id DESC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+	ErrAgentUnavailable       = errors.New("agent is not available")
+)
+
+type OrderRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewOrderRepository(db *gorm.DB) *OrderRepository {
+	return &OrderRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository that runs on the given transaction.
+func (r *OrderRepository) WithTx(tx *gorm.DB) *OrderRepository {
+	return &OrderRepository{
+		db:       tx,
+		tenantID: r.tenantID,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's orders.
+func (r *OrderRepository) ForTenant(tenantID string) *OrderRepository {
+	return &OrderRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *OrderRepository) Create(order *models.Order) error {
+	order.Status = models.OrderCreated
+	if ownsTenant(r.tenantID) {
+		order.TenantID = r.tenantID
+	}
+	return r.db.Create(order).Error
+}
+
+func (r *OrderRepository) FindByID(orderID uint) (*models.Order, error) {
+	var order models.Order
+
+	result := scopeTenant(r.db, "orders", r.tenantID).First(&order, orderID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &order, nil
+}
+
+func (r *OrderRepository) FindAll(limit, offset int, status, agentID string) ([]models.Order, int64, error) {
+	var orders []models.Order
+	var totalCount int64
+
+	query := scopeTenant(r.db, "orders", r.tenantID).Model(&models.Order{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if agentID != "" {
+		query = query.Where("agent_id = ?", agentID)
+	}
+
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&orders).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, totalCount, nil
+}
+
+// TransitionHook runs inside the transaction of an order status change,
+// after the order has been updated, so its writes commit or roll back
+// together with the change.
+type TransitionHook func(tx *gorm.DB, order models.Order) error
+
+// Transition moves an order if the lifecycle allows it and stamps the
+// matching timestamp column. The row is locked for the duration so
+// concurrent transitions on the same order are serialised. Assigning the
+// order claims the agent as busy, like dispatch does; an agent already busy
+// with other orders can take one more, any other status is refused with
+// ErrAgentUnavailable. When the order stops needing its agent (delivered,
+// failed, cancelled or unassigned) the agent is released back to available,
+// unless it still has other orders in progress. The hooks run last, in the
+// same transaction.
+func (r *OrderRepository) Transition(orderID uint, to, agentID, reason string, hooks ...TransitionHook) (*models.Order, error) {
+	var order models.Order
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		previousAgent, err := r.transition(tx, &order, orderID, to, agentID, reason)
+		if err != nil {
+			return err
+		}
+
+		if err := r.updateAgent(tx, orderID, to, agentID, previousAgent); err != nil {
+			return err
+		}
+
+		for _, hook := range hooks {
+			if err := hook(tx, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// updateAgent claims or releases the agent as the order moves to status to.
+func (r *OrderRepository) updateAgent(tx *gorm.DB, orderID uint, to, agentID, previousAgent string) error {
+	agentRepo := NewAgentRepository(tx).ForTenant(r.tenantID)
+	trigger := models.StatusTrigger{
+		Source: models.StatusSourceOrder,
+		Actor:  fmt.Sprintf("order:%d", orderID),
+		Reason: "order " + to,
+	}
+
+	switch to {
+	case models.OrderAssigned:
+		return claimAgent(agentRepo, agentID, trigger)
+	case models.OrderCreated, models.OrderDelivered, models.OrderFailed, models.OrderCancelled:
+		if previousAgent == "" {
+			return nil
+		}
+
+		active, err := r.WithTx(tx).CountActive(previousAgent)
+		if err != nil || active > 0 {
+			return err
+		}
+		return agentRepo.Release(previousAgent, trigger)
+	}
+	return nil
+}
+
+// claimAgent marks an available agent busy. An agent that is busy already
+// keeps its status.
+func claimAgent(agentRepo *AgentRepository, agentID string, trigger models.StatusTrigger) error {
+	claimed, err := agentRepo.ClaimAvailable(agentID, trigger)
+	if err != nil || claimed {
+		return err
+	}
+
+	agent, err := agentRepo.FindByID(agentID)
+	if err != nil {
+		return err
+	}
+
+	if agent == nil || !agent.IsActive || agent.Status != "busy" {
+		return fmt.Errorf("%w: %s", ErrAgentUnavailable, agentID)
+	}
+
+	return nil
+}
+
+// transition applies the status change on tx and returns the agent the
+// order was assigned to before the change.
+func (r *OrderRepository) transition(tx *gorm.DB, order *models.Order, orderID uint, to, agentID, reason string) (string, error) {
+	result := scopeTenant(tx, "orders", r.tenantID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(order, orderID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return "", ErrOrderNotFound
+		}
+		return "", result.Error
+	}
+
+	if !models.CanTransitionOrder(order.Status, to) {
+		return "", fmt.Errorf("%w: %s -> %s", ErrInvalidOrderTransition, order.Status, to)
+	}
+
+	previousAgent := order.AgentIDValue()
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status": to,
+	}
+
+	switch to {
+	case models.OrderCreated:
+		updates["agent_id"] = nil
+		updates["assigned_at"] = nil
+	case models.OrderAssigned:
+		if agentID == "" {
+			return "", fmt.Errorf("%w: agent_id is required to assign an order", ErrInvalidOrderTransition)
+		}
+		updates["agent_id"] = agentID
+		updates["assigned_at"] = now
+	case models.OrderPickedUp:
+		updates["picked_up_at"] = now
+	case models.OrderInTransit:
+		updates["in_transit_at"] = now
+	case models.OrderDelivered:
+		updates["delivered_at"] = now
+	case models.OrderFailed:
+		updates["failed_at"] = now
+		updates["failure_reason"] = reason
+	case models.OrderCancelled:
+		updates["cancelled_at"] = now
+		updates["failure_reason"] = reason
+	}
+
+	if err := tx.Model(order).Updates(updates).Error; err != nil {
+		return "", err
+	}
+
+	return previousAgent, tx.First(order, orderID).Error
+}
+
+// Assign moves a created order to assigned without opening its own
+// transaction; use it through WithTx to combine it with other writes.
+func (r *OrderRepository) Assign(orderID uint, agentID string) (*models.Order, error) {
+	var order models.Order
+
+	if _, err := r.transition(r.db, &order, orderID, models.OrderAssigned, agentID, ""); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// CountByStatus counts the orders in each status.
+func (r *OrderRepository) CountByStatus() (map[string]int64, error) {
+	var results []struct {
+		Status string
+		Count  int64
+	}
+
+	err := scopeTenant(r.db, "orders", r.tenantID).Model(&models.Order{}).
+		Select("status, COUNT(*) as count").
+		Group("status").
+		Find(&results).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	for _, result := range results {
+		counts[result.Status] = result.Count
+	}
+
+	return counts, nil
+}
+
+// CountActive counts the agent's orders that are assigned, picked up or in
+// transit.
+func (r *OrderRepository) CountActive(agentID string) (int64, error) {
+	var count int64
+
+	result := scopeTenant(r.db, "orders", r.tenantID).Model(&models.Order{}).
+		Where("agent_id = ? AND status IN ?", agentID,
+			[]string{models.OrderAssigned, models.OrderPickedUp, models.OrderInTransit}).
+		Count(&count)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (r *OrderRepository) CountDelivered(agentID string) (int64, error) {
+	var count int64
+
+	result := scopeTenant(r.db, "orders", r.tenantID).Model(&models.Order{}).
+		Where("agent_id = ? AND status = ?", agentID, models.OrderDelivered).
+		Count(&count)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
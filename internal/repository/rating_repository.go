@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrAlreadyRated = errors.New("order has already been rated")
+
+type RatingRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewRatingRepository(db *gorm.DB) *RatingRepository {
+	return &RatingRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's ratings.
+func (r *RatingRepository) ForTenant(tenantID string) *RatingRepository {
+	return &RatingRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *RatingRepository) scoped() *gorm.DB {
+	return scopeTenant(r.db, "ratings", r.tenantID)
+}
+
+// Create stores the rating, or returns ErrAlreadyRated if the order already
+// has one.
+func (r *RatingRepository) Create(rating *models.Rating) error {
+	if ownsTenant(r.tenantID) {
+		rating.TenantID = r.tenantID
+	}
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "order_id"}},
+		DoNothing: true,
+	}).Create(rating)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrAlreadyRated
+	}
+
+	return nil
+}
+
+func (r *RatingRepository) FindByOrder(orderID uint) (*models.Rating, error) {
+	var rating models.Rating
+
+	result := r.scoped().Where("order_id = ?", orderID).First(&rating)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &rating, nil
+}
+
+// FindByAgent returns the agent's ratings, newest first.
+func (r *RatingRepository) FindByAgent(agentID string, limit, offset int) ([]models.Rating, int64, error) {
+	var ratings []models.Rating
+	var total int64
+
+	query := r.scoped().Model(&models.Rating{}).Where("agent_id = ?", agentID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&ratings).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return ratings, total, nil
+}
+
+// Average returns the agent's average score and number of ratings received
+// in [from, to). A zero from or to leaves that end open.
+func (r *RatingRepository) Average(agentID string, from, to time.Time) (float64, int64, error) {
+	var result struct {
+		Average float64
+		Count   int64
+	}
+
+	query := r.scoped().Model(&models.Rating{}).
+		Select("COALESCE(AVG(score), 0) AS average, COUNT(*) AS count").
+		Where("agent_id = ?", agentID)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	if err := query.Scan(&result).Error; err != nil {
+		return 0, 0, err
+	}
+
+	return result.Average, result.Count, nil
+}
+
+// Distribution counts the agent's ratings per score.
+func (r *RatingRepository) Distribution(agentID string) (map[int]int64, error) {
+	var rows []struct {
+		Score int
+		Count int64
+	}
+
+	err := r.scoped().Model(&models.Rating{}).
+		Select("score, COUNT(*) AS count").
+		Where("agent_id = ?", agentID).
+		Group("score").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	distribution := make(map[int]int64, models.MaxRatingScore)
+	for score := models.MinRatingScore; score <= models.MaxRatingScore; score++ {
+		distribution[score] = 0
+	}
+	for _, row := range rows {
+		distribution[row.Score] = row.Count
+	}
+
+	return distribution, nil
+}
+
+// TagCounts counts how often each tag was given to the agent.
+func (r *RatingRepository) TagCounts(agentID string) (map[string]int64, error) {
+	var rows []struct {
+		Tag   string
+		Count int64
+	}
+
+	err := r.scoped().Model(&models.Rating{}).
+		Select("tag.value AS tag, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL jsonb_array_elements_text(ratings.tags) AS tag(value)").
+		Where("ratings.agent_id = ?", agentID).
+		Group("tag.value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		tags[row.Tag] = row.Count
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrShiftNotFound     = errors.New("shift not found")
+	ErrShiftOverlap      = errors.New("shift overlaps another shift of the agent")
+	ErrShiftNotScheduled = errors.New("only scheduled shifts can be cancelled")
+	ErrNoShiftToClockIn  = errors.New("no scheduled shift to clock in to")
+	ErrAlreadyClockedIn  = errors.New("agent is already clocked in")
+	ErrNotClockedIn      = errors.New("agent is not clocked in")
+	ErrAgentOnDelivery   = errors.New("agent is busy with a delivery")
+	ErrAgentInactive     = errors.New("agent is deactivated")
+)
+
+type ShiftRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewShiftRepository(db *gorm.DB) *ShiftRepository {
+	return &ShiftRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's shifts.
+func (r *ShiftRepository) ForTenant(tenantID string) *ShiftRepository {
+	return &ShiftRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *ShiftRepository) scoped(tx *gorm.DB) *gorm.DB {
+	return scopeTenant(tx, "shifts", r.tenantID)
+}
+
+// Create stores a scheduled shift unless it overlaps one of the agent's
+// other shifts that is not cancelled.
+func (r *ShiftRepository) Create(shift *models.Shift) error {
+	if ownsTenant(r.tenantID) {
+		shift.TenantID = r.tenantID
+	}
+	shift.Status = models.ShiftScheduled
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Serialise scheduling per agent so two overlapping shifts cannot
+		// both pass the check.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "shifts:"+shift.AgentID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		err := r.scoped(tx).Model(&models.Shift{}).
+			Where("agent_id = ? AND status <> ?", shift.AgentID, models.ShiftCancelled).
+			Where("starts_at < ? AND ends_at > ?", shift.EndsAt, shift.StartsAt).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrShiftOverlap
+		}
+
+		return tx.Create(shift).Error
+	})
+}
+
+func (r *ShiftRepository) FindByID(shiftID uint) (*models.Shift, error) {
+	var shift models.Shift
+
+	result := r.scoped(r.db).First(&shift, shiftID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &shift, nil
+}
+
+type ShiftFilter struct {
+	AgentID string
+	Status  string
+	From    time.Time // Shifts ending after From
+	To      time.Time // Shifts starting before To
+}
+
+// Find returns the shifts overlapping the filter's range, earliest first.
+func (r *ShiftRepository) Find(filter ShiftFilter) ([]models.Shift, error) {
+	var shifts []models.Shift
+
+	query := r.scoped(r.db).Where("starts_at < ? AND ends_at > ?", filter.To, filter.From)
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Order("starts_at ASC, id ASC").Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// FindAt returns the shifts that are not cancelled and cover the given time.
+func (r *ShiftRepository) FindAt(at time.Time) ([]models.Shift, error) {
+	var shifts []models.Shift
+
+	err := r.scoped(r.db).
+		Where("status <> ? AND starts_at <= ? AND ends_at > ?", models.ShiftCancelled, at, at).
+		Order("agent_id, starts_at ASC").
+		Find(&shifts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// Cancel cancels a shift that has not been clocked in to.
+func (r *ShiftRepository) Cancel(shiftID uint) (*models.Shift, error) {
+	var shift models.Shift
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := r.scoped(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&shift, shiftID)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				return ErrShiftNotFound
+			}
+			return result.Error
+		}
+
+		if shift.Status != models.ShiftScheduled {
+			return ErrShiftNotScheduled
+		}
+
+		shift.Status = models.ShiftCancelled
+		return tx.Model(&shift).Update("status", models.ShiftCancelled).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+// ClockIn starts the agent's scheduled shift that covers at, or starts
+// within opensBefore of it, and brings an offline agent to available. An
+// active shift that ended more than closeAfter ago is completed at its
+// planned end and flagged as a missed clock-out first; a more recent one
+// still blocks the clock-in. It returns the shift and the agent's status
+// before clocking in.
+func (r *ShiftRepository) ClockIn(agentID string, at time.Time, opensBefore, closeAfter time.Duration, trigger models.StatusTrigger) (*models.Shift, string, error) {
+	var shift models.Shift
+	var previous string
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := r.scoped(tx).Model(&models.Shift{}).
+			Where("agent_id = ? AND status = ? AND ends_at <= ?", agentID, models.ShiftActive, at.Add(-closeAfter)).
+			Updates(map[string]interface{}{
+				"status":           models.ShiftCompleted,
+				"clock_out_at":     gorm.Expr("ends_at"),
+				"missed_clock_out": true,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		var active int64
+		err = r.scoped(tx).Model(&models.Shift{}).
+			Where("agent_id = ? AND status = ?", agentID, models.ShiftActive).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+
+		if active > 0 {
+			return ErrAlreadyClockedIn
+		}
+
+		result := r.scoped(tx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ? AND status = ?", agentID, models.ShiftScheduled).
+			Where("starts_at <= ? AND ends_at > ?", at.Add(opensBefore), at).
+			Order("starts_at ASC").
+			First(&shift)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				return ErrNoShiftToClockIn
+			}
+			return result.Error
+		}
+
+		lateSecs := 0
+		if at.After(shift.StartsAt) {
+			lateSecs = int(at.Sub(shift.StartsAt).Seconds())
+		}
+
+		shift.Status = models.ShiftActive
+		shift.ClockInAt = &at
+		shift.LateSecs = lateSecs
+
+		err = tx.Model(&shift).Select("status", "clock_in_at", "late_secs").Updates(&shift).Error
+		if err != nil {
+			return err
+		}
+
+		agentRepo := NewAgentRepository(tx).ForTenant(r.tenantID)
+
+		agent, err := agentRepo.FindByID(agentID)
+		if err != nil {
+			return err
+		}
+		if agent == nil {
+			return errors.New("agent not found")
+		}
+		if !agent.IsActive {
+			return ErrAgentInactive
+		}
+
+		previous = agent.Status
+		if previous != "offline" {
+			return nil
+		}
+
+		_, err = agentRepo.UpdateStatus(agentID, "available", trigger)
+		return err
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &shift, previous, nil
+}
+
+// ClockOut ends the agent's active shift and takes the agent offline. An
+// agent busy with a delivery cannot clock out. It returns the shift and the
+// agent's status before clocking out.
+func (r *ShiftRepository) ClockOut(agentID string, at time.Time, trigger models.StatusTrigger) (*models.Shift, string, error) {
+	var shift models.Shift
+	var previous string
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := r.scoped(tx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ? AND status = ?", agentID, models.ShiftActive).
+			First(&shift)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				return ErrNotClockedIn
+			}
+			return result.Error
+		}
+
+		agentRepo := NewAgentRepository(tx).ForTenant(r.tenantID)
+
+		agent, err := agentRepo.FindByID(agentID)
+		if err != nil {
+			return err
+		}
+		if agent == nil {
+			return errors.New("agent not found")
+		}
+
+		if agent.Status == "busy" {
+			return ErrAgentOnDelivery
+		}
+
+		earlySecs := 0
+		if at.Before(shift.EndsAt) {
+			earlySecs = int(shift.EndsAt.Sub(at).Seconds())
+		}
+
+		shift.Status = models.ShiftCompleted
+		shift.ClockOutAt = &at
+		shift.EarlyLeaveSecs = earlySecs
+
+		err = tx.Model(&shift).Select("status", "clock_out_at", "early_leave_secs").Updates(&shift).Error
+		if err != nil {
+			return err
+		}
+
+		previous, err = agentRepo.UpdateStatus(agentID, "offline", trigger)
+		return err
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &shift, previous, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// StatusHistoryRepository reads the status transitions that
+// AgentRepository records.
+type StatusHistoryRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewStatusHistoryRepository(db *gorm.DB) *StatusHistoryRepository {
+	return &StatusHistoryRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees the tenant's
+// status history.
+func (r *StatusHistoryRepository) ForTenant(tenantID string) *StatusHistoryRepository {
+	return &StatusHistoryRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *StatusHistoryRepository) scoped() *gorm.DB {
+	return scopeTenant(r.db, "agent_status_changes", r.tenantID)
+}
+
+// FindByAgent returns the agent's transitions between from and to, newest
+// first.
+func (r *StatusHistoryRepository) FindByAgent(agentID string, from, to time.Time, limit int) ([]models.AgentStatusChange, error) {
+	var changes []models.AgentStatusChange
+
+	err := r.scoped().
+		Where("agent_id = ? AND timestamp >= ? AND timestamp <= ?", agentID, from, to).
+		Order("timestamp DESC, id DESC").
+		Limit(limit).
+		Find(&changes).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// FindInRange returns the transitions of the given agents after from and up
+// to to, in chronological order. No agent IDs means every agent.
+func (r *StatusHistoryRepository) FindInRange(agentIDs []string, from, to time.Time) ([]models.AgentStatusChange, error) {
+	var changes []models.AgentStatusChange
+
+	query := r.scoped().Where("timestamp > ? AND timestamp <= ?", from, to)
+	if len(agentIDs) > 0 {
+		query = query.Where("agent_id IN ?", agentIDs)
+	}
+
+	err := query.Order("agent_id, timestamp ASC, id ASC").Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// FindLatestBefore returns the last transition of each given agent at or
+// before the given time. No agent IDs means every agent.
+func (r *StatusHistoryRepository) FindLatestBefore(agentIDs []string, before time.Time) ([]models.AgentStatusChange, error) {
+	var changes []models.AgentStatusChange
+
+	query := r.scoped().
+		Select("DISTINCT ON (agent_id) *").
+		Where("timestamp <= ?", before)
+	if len(agentIDs) > 0 {
+		query = query.Where("agent_id IN ?", agentIDs)
+	}
+
+	err := query.Order("agent_id, timestamp DESC, id DESC").Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
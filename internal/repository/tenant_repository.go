@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AllTenants can be passed to ForTenant by startup code and background jobs
+// that work across tenants on already-authorised IDs.
+const AllTenants = "*"
+
+// scopeTenant restricts queries on table to the tenant's rows. Repositories
+// obtained through ForTenant route every query through it. A repository with
+// no tenant fails closed and sees nothing; cross-tenant work has to ask for
+// AllTenants explicitly.
+func scopeTenant(db *gorm.DB, table, tenantID string) *gorm.DB {
+	switch tenantID {
+	case AllTenants:
+		return db
+	case "":
+		return db.Where("1 = 0")
+	}
+	return db.Where(table+".tenant_id = ?", tenantID)
+}
+
+// ownsTenant reports whether rows created through a repository scoped to
+// tenantID should be stamped with it.
+func ownsTenant(tenantID string) bool {
+	return tenantID != "" && tenantID != AllTenants
+}
+
+type TenantRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantRepository(db *gorm.DB) *TenantRepository {
+	return &TenantRepository{
+		db: db,
+	}
+}
+
+func (r *TenantRepository) Create(tenant *models.Tenant) error {
+	var existing models.Tenant
+	result := r.db.Where("id = ?", tenant.ID).First(&existing)
+
+	if result.Error == nil {
+		return errors.New("tenant with this ID already exists")
+	}
+
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return r.db.Create(tenant).Error
+}
+
+// CreateWithAdmin creates the tenant and its first admin user together.
+func (r *TenantRepository) CreateWithAdmin(tenant *models.Tenant, admin *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := (&TenantRepository{db: tx}).Create(tenant); err != nil {
+			return err
+		}
+
+		admin.TenantID = tenant.ID
+		return NewUserRepository(tx).Create(admin)
+	})
+}
+
+func (r *TenantRepository) FindByID(tenantID string) (*models.Tenant, error) {
+	var tenant models.Tenant
+
+	result := r.db.Where("id = ?", tenantID).First(&tenant)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &tenant, nil
+}
+
+func (r *TenantRepository) FindAll() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+
+	if err := r.db.Order("created_at ASC").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+// EnsureDefault creates the default tenant that pre-existing rows belong to.
+func (r *TenantRepository) EnsureDefault() error {
+	tenant, err := r.FindByID(models.DefaultTenantID)
+	if err != nil || tenant != nil {
+		return err
+	}
+
+	return r.db.Create(&models.Tenant{
+		ID:       models.DefaultTenantID,
+		Name:     "Default",
+		IsActive: true,
+	}).Error
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db       *gorm.DB
+	tenantID string
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository that only sees and creates the
+// tenant's users. Sign-in looks users up across tenants, since emails are
+// unique deployment-wide.
+func (r *UserRepository) ForTenant(tenantID string) *UserRepository {
+	return &UserRepository{
+		db:       r.db,
+		tenantID: tenantID,
+	}
+}
+
+func (r *UserRepository) Create(user *models.User) error {
+	if ownsTenant(r.tenantID) {
+		user.TenantID = r.tenantID
+	}
+
+	var existing models.User
+	result := r.db.Where("email = ?", user.Email).First(&existing)
+
+	if result.Error == nil {
+		return errors.New("user with this email already exists")
+	}
+
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return r.db.Create(user).Error
+}
+
+func (r *UserRepository) FindByID(userID uint) (*models.User, error) {
+	var user models.User
+
+	result := scopeTenant(r.db, "users", r.tenantID).First(&user, userID)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+
+	result := r.db.Where("email = ?", email).First(&user)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) Count() (int64, error) {
+	var count int64
+
+	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *UserRepository) CountByRole(role string) (int64, error) {
+	var count int64
+
+	err := scopeTenant(r.db, "users", r.tenantID).Model(&models.User{}).
+		Where("role = ? AND is_active = ?", role, true).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *UserRepository) UpdateRole(userID uint, role string) error {
+	result := scopeTenant(r.db, "users", r.tenantID).Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("role", role)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// ConsumeRefreshToken revokes the token if it is still valid and returns it.
+// The revocation is a conditional update, so a token can only be used once
+// even under concurrent refresh attempts.
+func (r *UserRepository) ConsumeRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	now := time.Now()
+
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("revoked_at", now)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var token models.RefreshToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *UserRepository) RevokeRefreshTokens(userID uint) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoAgentAvailable = errors.New("no available agent matches the dispatch criteria")
+	errAgentTaken       = errors.New("agent already claimed")
+)
+
+const (
+	defaultMaxFixAge    = 10 * time.Minute
+	vehiclePenaltyMeter = 1000.0
+)
+
+type DispatchOptions struct {
+	VehicleTypes []string      // Allowed vehicle types in order of preference, empty allows all
+	MaxRadiusM   float64       // Ignore agents further than this from pickup, 0 means no limit
+	MaxFixAge    time.Duration // Ignore agents whose latest fix is older than this
+}
+
+type DispatchCandidate struct {
+	Agent     models.DeliveryAgent
+	Location  models.Location
+	DistanceM float64
+	score     float64
+}
+
+type DispatchResult struct {
+	Order     models.Order
+	Agent     models.DeliveryAgent
+	DistanceM float64
+	Evaluated int
+}
+
+type DispatchService struct {
+	db           *gorm.DB
+	agentRepo    *repository.AgentRepository
+	locationRepo *repository.LocationRepository
+	orderRepo    *repository.OrderRepository
+}
+
+func NewDispatchService(db *gorm.DB, agentRepo *repository.AgentRepository, locationRepo *repository.LocationRepository, orderRepo *repository.OrderRepository) *DispatchService {
+	return &DispatchService{
+		db:           db,
+		agentRepo:    agentRepo,
+		locationRepo: locationRepo,
+		orderRepo:    orderRepo,
+	}
+}
+
+// Rank returns the tenant's available agents with a recent fix, best first.
+func (s *DispatchService) Rank(tenantID string, lat, lng float64, opts DispatchOptions) ([]DispatchCandidate, error) {
+	agents, err := s.agentRepo.ForTenant(tenantID).FindAvailable(opts.VehicleTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	agentIDs := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		agentIDs = append(agentIDs, agent.ID)
+	}
+
+	latest, err := s.locationRepo.ForTenant(tenantID).FindLatestByAgentIDs(agentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankCandidates(agents, latest, lat, lng, opts, time.Now()), nil
+}
+
+// rankCandidates orders the agents by distance from their latest location
+// to the pickup point, dropping agents without a fix newer than MaxFixAge or
+// outside MaxRadiusM. Agents on a less preferred vehicle type are penalised
+// by vehiclePenaltyMeter per rank.
+func rankCandidates(agents []models.DeliveryAgent, latest []models.Location, lat, lng float64, opts DispatchOptions, now time.Time) []DispatchCandidate {
+	if opts.MaxFixAge <= 0 {
+		opts.MaxFixAge = defaultMaxFixAge
+	}
+
+	locations := make(map[string]models.Location, len(latest))
+	for _, loc := range latest {
+		locations[loc.AgentID] = loc
+	}
+
+	preference := make(map[string]int, len(opts.VehicleTypes))
+	for i, vehicleType := range opts.VehicleTypes {
+		preference[vehicleType] = i
+	}
+
+	cutoff := now.Add(-opts.MaxFixAge)
+	candidates := make([]DispatchCandidate, 0, len(agents))
+
+	for _, agent := range agents {
+		loc, ok := locations[agent.ID]
+		if !ok || loc.Timestamp.Before(cutoff) {
+			continue
+		}
+
+		distance := geo.HaversineMeters(lat, lng, loc.Latitude, loc.Longitude)
+		if opts.MaxRadiusM > 0 && distance > opts.MaxRadiusM {
+			continue
+		}
+
+		candidates = append(candidates, DispatchCandidate{
+			Agent:     agent,
+			Location:  loc,
+			DistanceM: distance,
+			score:     distance + float64(preference[agent.VehicleType])*vehiclePenaltyMeter,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+
+	return candidates
+}
+
+// Dispatch assigns the order to the best ranked agent. Claiming the agent
+// and assigning the order happen in one transaction, and the claim only
+// succeeds while the agent is still available, so an agent taken by a
+// concurrent dispatch is skipped in favour of the next candidate.
+func (s *DispatchService) Dispatch(tenantID string, orderID uint, opts DispatchOptions) (*DispatchResult, error) {
+	agentRepo := s.agentRepo.ForTenant(tenantID)
+	orderRepo := s.orderRepo.ForTenant(tenantID)
+
+	order, err := orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order == nil {
+		return nil, repository.ErrOrderNotFound
+	}
+
+	if !models.CanTransitionOrder(order.Status, models.OrderAssigned) {
+		return nil, repository.ErrInvalidOrderTransition
+	}
+
+	candidates, err := s.Rank(tenantID, order.PickupLatitude, order.PickupLongitude, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		var assigned *models.Order
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			claimed, err := agentRepo.WithTx(tx).ClaimAvailable(candidate.Agent.ID, models.StatusTrigger{
+				Source: models.StatusSourceDispatch,
+				Actor:  fmt.Sprintf("order:%d", orderID),
+				Reason: "assigned by dispatch",
+			})
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return errAgentTaken
+			}
+
+			assigned, err = orderRepo.WithTx(tx).Assign(orderID, candidate.Agent.ID)
+			return err
+		})
+
+		if errors.Is(err, errAgentTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		agent := candidate.Agent
+		agent.Status = "busy"
+
+		return &DispatchResult{
+			Order:     *assigned,
+			Agent:     agent,
+			DistanceM: candidate.DistanceM,
+			Evaluated: len(candidates),
+		}, nil
+	}
+
+	return nil, ErrNoAgentAvailable
+}
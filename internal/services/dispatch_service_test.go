@@ -0,0 +1,101 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestRankCandidates(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	const metersPerDegree = 111195.0
+
+	agents := []models.DeliveryAgent{
+		{ID: "bike-near", VehicleType: "bike"},
+		{ID: "bike-far", VehicleType: "bike"},
+		{ID: "car-nearest", VehicleType: "car"},
+		{ID: "bike-stale", VehicleType: "bike"},
+		{ID: "bike-no-fix", VehicleType: "bike"},
+	}
+
+	// fix places the agent the given meters north of the pickup point.
+	fix := func(agentID string, meters float64, age time.Duration) models.Location {
+		return models.Location{
+			AgentID:   agentID,
+			Latitude:  12.97 + meters/metersPerDegree,
+			Longitude: 77.59,
+			Timestamp: now.Add(-age),
+		}
+	}
+	latest := []models.Location{
+		fix("bike-near", 800, time.Minute),
+		fix("bike-far", 2000, time.Minute),
+		fix("car-nearest", 100, time.Minute),
+		fix("bike-stale", 50, 20*time.Minute),
+	}
+
+	tests := []struct {
+		name string
+		opts DispatchOptions
+		want []string
+	}{
+		{
+			name: "nearest first, stale and missing fixes dropped",
+			want: []string{"car-nearest", "bike-near", "bike-far"},
+		},
+		{
+			name: "less preferred vehicle is penalised",
+			opts: DispatchOptions{VehicleTypes: []string{"bike", "car"}},
+			want: []string{"bike-near", "car-nearest", "bike-far"},
+		},
+		{
+			name: "penalty grows with the preference rank",
+			opts: DispatchOptions{VehicleTypes: []string{"bike", "scooter", "car"}},
+			want: []string{"bike-near", "bike-far", "car-nearest"},
+		},
+		{
+			name: "penalty applies to every agent on a less preferred vehicle",
+			opts: DispatchOptions{VehicleTypes: []string{"car", "bike"}},
+			want: []string{"car-nearest", "bike-near", "bike-far"},
+		},
+		{
+			name: "longer max fix age keeps older fixes",
+			opts: DispatchOptions{MaxFixAge: 30 * time.Minute},
+			want: []string{"bike-stale", "car-nearest", "bike-near", "bike-far"},
+		},
+		{
+			name: "shorter max fix age drops recent fixes",
+			opts: DispatchOptions{MaxFixAge: 30 * time.Second},
+		},
+		{
+			name: "radius cuts off distant agents",
+			opts: DispatchOptions{MaxRadiusM: 1000},
+			want: []string{"car-nearest", "bike-near"},
+		},
+		{
+			name: "radius keeps only the nearest",
+			opts: DispatchOptions{MaxRadiusM: 150},
+			want: []string{"car-nearest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := rankCandidates(agents, latest, 12.97, 77.59, tt.opts, now)
+
+			var got []string
+			for _, candidate := range candidates {
+				got = append(got, candidate.Agent.ID)
+				if candidate.Location.AgentID != candidate.Agent.ID {
+					t.Errorf("%s ranked with the location of %s", candidate.Agent.ID, candidate.Location.AgentID)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranking = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+const distanceChunkSize = 5000
+
+type DistanceConfig struct {
+	MaxAccuracyM float64 // Fixes reporting a worse accuracy are ignored; 0 accuracy means unknown and is kept
+	MinStepM     float64 // Movements shorter than this from the last counted point are treated as jitter
+}
+
+func DefaultDistanceConfig() DistanceConfig {
+	return DistanceConfig{
+		MaxAccuracyM: 50,
+		MinStepM:     10,
+	}
+}
+
+// odometer folds fixes into a travelled distance. It only advances its
+// anchor once the agent has moved at least MinStepM, so GPS jitter around a
+// stationary point does not add up.
+type odometer struct {
+	cfg       DistanceConfig
+	meters    float64
+	anchorLat float64
+	anchorLng float64
+	hasAnchor bool
+}
+
+func (o *odometer) add(loc models.Location) {
+	if loc.Quality == models.QualitySuspect {
+		return
+	}
+
+	if o.cfg.MaxAccuracyM > 0 && loc.Accuracy > o.cfg.MaxAccuracyM {
+		return
+	}
+
+	if !o.hasAnchor {
+		o.anchorLat, o.anchorLng, o.hasAnchor = loc.Latitude, loc.Longitude, true
+		return
+	}
+
+	step := geo.HaversineMeters(o.anchorLat, o.anchorLng, loc.Latitude, loc.Longitude)
+	if step < o.cfg.MinStepM {
+		return
+	}
+
+	o.meters += step
+	o.anchorLat, o.anchorLng = loc.Latitude, loc.Longitude
+}
+
+// PathDistance returns the distance travelled along the given fixes, which
+// must be in chronological order.
+func PathDistance(locations []models.Location, cfg DistanceConfig) float64 {
+	o := odometer{cfg: cfg}
+	for _, loc := range locations {
+		o.add(loc)
+	}
+	return o.meters
+}
+
+// DistanceService reports travelled distance per agent. Lifetime totals are
+// kept in an accumulator that only folds in points newer than the last one
+// it has seen, so each call reads just the new tail of the history. When a
+// point is stored with an older timestamp, from a batch replay or an import,
+// the accumulator is rebuilt from the start of the history. Windowed
+// queries always recompute from the stored points.
+type DistanceService struct {
+	distanceRepo *repository.DistanceRepository
+	locationRepo *repository.LocationRepository
+	cfg          DistanceConfig
+
+	agentLocks sync.Map
+}
+
+func NewDistanceService(distanceRepo *repository.DistanceRepository, locationRepo *repository.LocationRepository, cfg DistanceConfig) *DistanceService {
+	return &DistanceService{
+		distanceRepo: distanceRepo,
+		locationRepo: locationRepo,
+		cfg:          cfg,
+	}
+}
+
+func (s *DistanceService) lockAgent(agentID string) func() {
+	value, _ := s.agentLocks.LoadOrStore(agentID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Lifetime brings the agent's accumulator up to date and returns the total
+// distance in meters.
+func (s *DistanceService) Lifetime(agentID string) (float64, error) {
+	unlock := s.lockAgent(agentID)
+	defer unlock()
+
+	acc, err := s.distanceRepo.Find(agentID)
+	if err != nil {
+		return 0, err
+	}
+
+	if acc == nil {
+		acc = &models.AgentDistance{AgentID: agentID}
+	}
+
+	if acc.LastID != 0 {
+		stale, err := s.locationRepo.HasInsertedBefore(agentID, acc.MaxID, acc.LastTimestamp, acc.LastID)
+		if err != nil {
+			return 0, err
+		}
+
+		if stale {
+			acc = &models.AgentDistance{AgentID: agentID}
+		}
+	}
+
+	o := odometer{
+		cfg:       s.cfg,
+		meters:    acc.TotalMeters,
+		anchorLat: acc.AnchorLat,
+		anchorLng: acc.AnchorLng,
+		hasAnchor: acc.HasAnchor,
+	}
+
+	updated := false
+	for {
+		locations, err := s.locationRepo.FindAfter(agentID, acc.LastTimestamp, acc.LastID, distanceChunkSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, loc := range locations {
+			o.add(loc)
+			acc.LastTimestamp = loc.Timestamp
+			acc.LastID = loc.ID
+			if loc.ID > acc.MaxID {
+				acc.MaxID = loc.ID
+			}
+		}
+
+		if len(locations) > 0 {
+			updated = true
+		}
+
+		if len(locations) < distanceChunkSize {
+			break
+		}
+	}
+
+	if updated {
+		acc.TotalMeters = o.meters
+		acc.AnchorLat, acc.AnchorLng, acc.HasAnchor = o.anchorLat, o.anchorLng, o.hasAnchor
+
+		if err := s.distanceRepo.Save(acc); err != nil {
+			return 0, err
+		}
+	}
+
+	return acc.TotalMeters, nil
+}
+
+// Window returns the distance travelled between from and to in meters,
+// reading the points in chunks.
+func (s *DistanceService) Window(agentID string, from, to time.Time) (float64, error) {
+	o := odometer{cfg: s.cfg}
+
+	after, afterID := from, uint(0)
+	for {
+		locations, err := s.locationRepo.FindRangeAfter(agentID, after, afterID, to, distanceChunkSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, loc := range locations {
+			o.add(loc)
+		}
+
+		if len(locations) < distanceChunkSize {
+			break
+		}
+
+		last := locations[len(locations)-1]
+		after, afterID = last.Timestamp, last.ID
+	}
+
+	return o.meters, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestPathDistance(t *testing.T) {
+	cfg := DefaultDistanceConfig() // 50 m accuracy, 10 m steps
+
+	// 0.0001 degrees of latitude is about 11.1 m.
+	fix := func(lat float64) models.Location {
+		return models.Location{Latitude: 12.97 + lat, Longitude: 77.59, Accuracy: 5}
+	}
+	meters := func(fromLat, toLat float64) float64 {
+		return geo.HaversineMeters(12.97+fromLat, 77.59, 12.97+toLat, 77.59)
+	}
+
+	poor := fix(0.001)
+	poor.Accuracy = 80
+	suspect := fix(0.001)
+	suspect.Quality = models.QualitySuspect
+	unknownAccuracy := fix(0.0002)
+	unknownAccuracy.Accuracy = 0
+
+	tests := []struct {
+		name      string
+		locations []models.Location
+		want      float64
+	}{
+		{"no fixes", nil, 0},
+		{"single fix", []models.Location{fix(0)}, 0},
+		{"straight line", []models.Location{fix(0), fix(0.0002), fix(0.0004)}, meters(0, 0.0004)},
+		{"jitter below the minimum step", []models.Location{fix(0), fix(0.00005), fix(-0.00005), fix(0.00003)}, 0},
+		{"slow drift adds up once it clears the step", []models.Location{fix(0), fix(0.00005), fix(0.0001)}, meters(0, 0.0001)},
+		{"poor accuracy is ignored", []models.Location{fix(0), poor, fix(0.0002)}, meters(0, 0.0002)},
+		{"suspect fixes are ignored", []models.Location{fix(0), suspect, fix(0.0002)}, meters(0, 0.0002)},
+		{"unknown accuracy is kept", []models.Location{fix(0), unknownAccuracy}, meters(0, 0.0002)},
+		{"poor first fix does not anchor", []models.Location{poor, fix(0), fix(0.0002)}, meters(0, 0.0002)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PathDistance(tt.locations, cfg); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("distance = %.3f m, want %.3f m", got, tt.want)
+			}
+		})
+	}
+}
+
+// The lifetime accumulator resumes an odometer from its stored anchor; that
+// must give the same total as folding the whole history at once.
+func TestOdometerResumes(t *testing.T) {
+	cfg := DefaultDistanceConfig()
+
+	var locations []models.Location
+	for i, step := range []float64{0, 0.00005, 0.00012, 0.00013, 0.0003, 0.00031, 0.0005, 0.0004} {
+		locations = append(locations, models.Location{ID: uint(i + 1), Latitude: 12.97 + step, Longitude: 77.59, Accuracy: 5})
+	}
+
+	whole := PathDistance(locations, cfg)
+
+	for split := 0; split <= len(locations); split++ {
+		first := odometer{cfg: cfg}
+		for _, loc := range locations[:split] {
+			first.add(loc)
+		}
+
+		resumed := odometer{
+			cfg:       cfg,
+			meters:    first.meters,
+			anchorLat: first.anchorLat,
+			anchorLng: first.anchorLng,
+			hasAnchor: first.hasAnchor,
+		}
+		for _, loc := range locations[split:] {
+			resumed.add(loc)
+		}
+
+		if math.Abs(resumed.meters-whole) > 1e-6 {
+			t.Errorf("split at %d: %.3f m, want %.3f m", split, resumed.meters, whole)
+		}
+	}
+}
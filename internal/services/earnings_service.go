@@ -0,0 +1,316 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// EarningsService prices delivered orders with the tenant's pay rules and
+// keeps the agents' earnings ledgers up to date.
+//
+// A delivery earns the rule's base plus its per-km rate for the distance
+// tracked between pickup and drop-off, times the highest peak multiplier in
+// force when it was delivered. Each delivery is priced when the order moves
+// to delivered, in the same transaction, with the rule in force at that
+// moment; a delivery made while no rule applied earns nothing, and reading
+// earnings never writes to the ledger.
+type EarningsService struct {
+	earningsRepo    *repository.EarningsRepository
+	agentRepo       *repository.AgentRepository
+	distanceService *DistanceService
+}
+
+func NewEarningsService(earningsRepo *repository.EarningsRepository, agentRepo *repository.AgentRepository, distanceService *DistanceService) *EarningsService {
+	return &EarningsService{
+		earningsRepo:    earningsRepo,
+		agentRepo:       agentRepo,
+		distanceService: distanceService,
+	}
+}
+
+// RecordDelivery adds the pay for a delivered order to its agent's ledger on
+// tx, together with any daily target bonus it completes. Orders in any other
+// status and orders already in the ledger are skipped. It is meant to run as
+// a repository.TransitionHook.
+func (s *EarningsService) RecordDelivery(tx *gorm.DB, order models.Order) error {
+	agentID := order.AgentIDValue()
+	if order.Status != models.OrderDelivered || agentID == "" || order.DeliveredAt == nil {
+		return nil
+	}
+
+	earningsRepo := s.earningsRepo.WithTx(tx).ForTenant(order.TenantID)
+
+	agent, err := s.agentRepo.WithTx(tx).ForTenant(order.TenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return err
+	}
+
+	rule, err := earningsRepo.FindRuleFor(agent.VehicleType)
+	if err != nil || rule == nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(rule.Timezone)
+	if err != nil {
+		return fmt.Errorf("pay rule %d: %w", rule.ID, err)
+	}
+
+	start := *order.DeliveredAt
+	if order.PickedUpAt != nil {
+		start = *order.PickedUpAt
+	} else if order.AssignedAt != nil {
+		start = *order.AssignedAt
+	}
+
+	meters, err := s.distanceService.Window(agentID, start, *order.DeliveredAt)
+	if err != nil {
+		return err
+	}
+
+	distanceKm := metersToKm(meters)
+	multiplier := peakMultiplier(rule.PeakWindows, order.DeliveredAt.In(loc))
+	distancePay := roundMoney(distanceKm * rule.PerKm)
+
+	reference := fmt.Sprintf("order:%d", order.ID)
+	orderID := order.ID
+	ruleID := rule.ID
+
+	added, err := earningsRepo.CreateEntry(&models.EarningsEntry{
+		AgentID:     agentID,
+		Type:        models.EarningDelivery,
+		Reference:   &reference,
+		OrderID:     &orderID,
+		PayRuleID:   &ruleID,
+		Base:        rule.BasePerDelivery,
+		DistanceKm:  distanceKm,
+		DistancePay: distancePay,
+		Multiplier:  multiplier,
+		Amount:      roundMoney((rule.BasePerDelivery + distancePay) * multiplier),
+		Description: fmt.Sprintf("Delivery of order %d", order.ID),
+		EarnedAt:    *order.DeliveredAt,
+	})
+	if err != nil || !added {
+		return err
+	}
+
+	return s.recordTargets(earningsRepo, rule, agentID, order.DeliveredAt.In(loc))
+}
+
+// recordTargets pays the daily targets the agent has reached on the day of
+// the given delivery time.
+func (s *EarningsService) recordTargets(earningsRepo *repository.EarningsRepository, rule *models.PayRule, agentID string, at time.Time) error {
+	if len(rule.DailyTargets) == 0 {
+		return nil
+	}
+
+	dayStart, dayEnd := localDay(at)
+
+	count, err := earningsRepo.CountDeliveries(agentID, dayStart, dayEnd)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range reachedTargets(rule.DailyTargets, count) {
+		day := dayStart.Format("2006-01-02")
+		reference := fmt.Sprintf("target:%s:%s:%d", agentID, day, target.Deliveries)
+		ruleID := rule.ID
+
+		_, err := earningsRepo.CreateEntry(&models.EarningsEntry{
+			AgentID:     agentID,
+			Type:        models.EarningTargetBonus,
+			Reference:   &reference,
+			PayRuleID:   &ruleID,
+			Multiplier:  1,
+			Amount:      roundMoney(target.Bonus),
+			Description: fmt.Sprintf("%d deliveries on %s", target.Deliveries, day),
+			EarnedAt:    at,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Summary totals the agent's ledger over [from, to).
+func (s *EarningsService) Summary(tenantID, agentID string, from, to time.Time, limit int) (*models.EarningsSummary, error) {
+	earningsRepo := s.earningsRepo.ForTenant(tenantID)
+
+	totals, err := earningsRepo.Totals(agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	unpaid, err := earningsRepo.UnpaidTotal(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := earningsRepo.FindEntries(agentID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.EarningsSummary{
+		AgentID: agentID,
+		From:    from,
+		To:      to,
+		ByType:  make(map[string]float64, len(totals)),
+		Unpaid:  roundMoney(unpaid),
+		Entries: EntryResponses(entries),
+	}
+
+	for _, total := range totals {
+		summary.ByType[total.Type] = roundMoney(total.Amount)
+		summary.Total += total.Amount
+		if total.Type == models.EarningDelivery {
+			summary.Deliveries = total.Count
+		}
+	}
+	summary.Total = roundMoney(summary.Total)
+
+	return summary, nil
+}
+
+// Total returns the agent's ledger total over [from, to), or over the whole
+// ledger when both are zero.
+func (s *EarningsService) Total(tenantID, agentID string, from, to time.Time) (float64, error) {
+	totals, err := s.earningsRepo.ForTenant(tenantID).Totals(agentID, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := 0.0
+	for _, total := range totals {
+		sum += total.Amount
+	}
+
+	return roundMoney(sum), nil
+}
+
+// CreatePayout settles the agent's unpaid entries earned in [from, to) in a
+// payout statement.
+func (s *EarningsService) CreatePayout(tenantID, agentID string, from, to time.Time, createdBy string) (*models.Payout, error) {
+	payout := &models.Payout{
+		AgentID:     agentID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		CreatedBy:   createdBy,
+	}
+
+	if err := s.earningsRepo.ForTenant(tenantID).CreatePayout(payout); err != nil {
+		return nil, err
+	}
+
+	return payout, nil
+}
+
+func EntryResponses(entries []models.EarningsEntry) []models.EarningsEntryResponse {
+	responses := make([]models.EarningsEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, models.EarningsEntryResponse{
+			ID:          entry.ID,
+			Type:        entry.Type,
+			OrderID:     entry.OrderID,
+			Base:        entry.Base,
+			DistanceKm:  entry.DistanceKm,
+			DistancePay: entry.DistancePay,
+			Multiplier:  entry.Multiplier,
+			Amount:      entry.Amount,
+			Description: entry.Description,
+			CreatedBy:   entry.CreatedBy,
+			PayoutID:    entry.PayoutID,
+			EarnedAt:    entry.EarnedAt,
+		})
+	}
+	return responses
+}
+
+// localDay returns the start and end of the calendar day containing t, in
+// t's location.
+func localDay(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// reachedTargets returns the daily targets met by count deliveries.
+func reachedTargets(targets []models.DeliveryTarget, count int64) []models.DeliveryTarget {
+	var reached []models.DeliveryTarget
+	for _, target := range targets {
+		if int64(target.Deliveries) <= count {
+			reached = append(reached, target)
+		}
+	}
+	return reached
+}
+
+// peakMultiplier returns the highest multiplier of the windows covering t,
+// or 1 outside peak hours. t must be in the rule's timezone.
+func peakMultiplier(windows []models.PeakWindow, t time.Time) float64 {
+	multiplier := 1.0
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, window := range windows {
+		start, err1 := ParseClock(window.Start)
+		end, err2 := ParseClock(window.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		day := t.Weekday()
+		inside := false
+		if start <= end {
+			inside = minute >= start && minute < end
+		} else {
+			// Wraps past midnight; the early part belongs to the previous day.
+			switch {
+			case minute >= start:
+				inside = true
+			case minute < end:
+				inside = true
+				day = (day + 6) % 7
+			}
+		}
+
+		if inside && onDay(window.Days, day) && window.Multiplier > multiplier {
+			multiplier = window.Multiplier
+		}
+	}
+
+	return multiplier
+}
+
+func onDay(days []time.Weekday, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClock parses an HH:MM time of day into minutes after midnight.
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func metersToKm(meters float64) float64 {
+	return math.Round(meters) / 1000
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestPeakMultiplier(t *testing.T) {
+	// 2026-03-06 is a Friday.
+	at := func(day int, clock string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", fmt.Sprintf("2026-03-%02d %s", day, clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	lunch := models.PeakWindow{Start: "12:00", End: "14:00", Multiplier: 1.5}
+	lateNightFriday := models.PeakWindow{Days: []time.Weekday{time.Friday}, Start: "22:00", End: "02:00", Multiplier: 2}
+	weekendLunch := models.PeakWindow{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: "11:00", End: "15:00", Multiplier: 1.8}
+
+	tests := []struct {
+		name    string
+		windows []models.PeakWindow
+		t       time.Time
+		want    float64
+	}{
+		{"no windows", nil, at(6, "12:30"), 1},
+		{"inside a window", []models.PeakWindow{lunch}, at(6, "12:30"), 1.5},
+		{"start is inclusive", []models.PeakWindow{lunch}, at(6, "12:00"), 1.5},
+		{"end is exclusive", []models.PeakWindow{lunch}, at(6, "14:00"), 1},
+		{"wrapping window before midnight", []models.PeakWindow{lateNightFriday}, at(6, "23:30"), 2},
+		{"wrapping window after midnight counts for the previous day", []models.PeakWindow{lateNightFriday}, at(7, "01:30"), 2},
+		{"wrapping window after midnight on the wrong previous day", []models.PeakWindow{lateNightFriday}, at(6, "01:30"), 1},
+		{"wrapping window late on the wrong day", []models.PeakWindow{lateNightFriday}, at(7, "23:30"), 1},
+		{"wrapping window end is exclusive", []models.PeakWindow{lateNightFriday}, at(7, "02:00"), 1},
+		{"overlapping windows, highest wins", []models.PeakWindow{lunch, weekendLunch}, at(7, "12:30"), 1.8},
+		{"overlapping windows in either order", []models.PeakWindow{weekendLunch, lunch}, at(7, "12:30"), 1.8},
+		{"only the matching overlap applies", []models.PeakWindow{weekendLunch, lunch}, at(6, "12:30"), 1.5},
+		{"multiplier below 1 is ignored", []models.PeakWindow{{Start: "12:00", End: "14:00", Multiplier: 0.5}}, at(6, "12:30"), 1},
+		{"invalid clock is skipped", []models.PeakWindow{{Start: "25:00", End: "14:00", Multiplier: 3}, lunch}, at(6, "12:30"), 1.5},
+		{"malformed clock is skipped", []models.PeakWindow{{Start: "noon", End: "2pm", Multiplier: 3}}, at(6, "12:30"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peakMultiplier(tt.windows, tt.t); got != tt.want {
+				t.Errorf("multiplier = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachedTargets(t *testing.T) {
+	targets := []models.DeliveryTarget{{Deliveries: 10, Bonus: 100}, {Deliveries: 20, Bonus: 250}}
+
+	tests := []struct {
+		name  string
+		count int64
+		want  []int
+	}{
+		{"below every target", 9, nil},
+		{"target reached exactly", 10, []int{10}},
+		{"between targets", 19, []int{10}},
+		{"every target", 25, []int{10, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := reachedTargets(targets, tt.count)
+			if len(reached) != len(tt.want) {
+				t.Fatalf("reached %+v, want %v deliveries", reached, tt.want)
+			}
+			for i, target := range reached {
+				if target.Deliveries != tt.want[i] {
+					t.Errorf("target %d = %d deliveries, want %d", i, target.Deliveries, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLocalDay(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	// 20:00 UTC is already the next day in Kolkata.
+	start, end := localDay(time.Date(2026, 3, 6, 20, 0, 0, 0, time.UTC).In(kolkata))
+
+	if want := time.Date(2026, 3, 7, 0, 0, 0, 0, kolkata); !start.Equal(want) {
+		t.Errorf("start = %v, want %v", start, want)
+	}
+	if want := time.Date(2026, 3, 8, 0, 0, 0, 0, kolkata); !end.Equal(want) {
+		t.Errorf("end = %v, want %v", end, want)
+	}
+}
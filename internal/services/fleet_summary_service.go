@@ -0,0 +1,136 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+type FleetSummaryConfig struct {
+	CacheTTL   time.Duration // How long a summary is served before it is rebuilt
+	StaleAfter time.Duration // Latest fixes older than this count as stale
+}
+
+func DefaultFleetSummaryConfig() FleetSummaryConfig {
+	return FleetSummaryConfig{
+		CacheTTL:   10 * time.Second,
+		StaleAfter: 5 * time.Minute,
+	}
+}
+
+// FleetSummaryService builds the fleet dashboard summary. Summaries are
+// cached per tenant for CacheTTL, and concurrent requests for an expired
+// summary wait for a single rebuild.
+type FleetSummaryService struct {
+	agentRepo    *repository.AgentRepository
+	locationRepo *repository.LocationRepository
+	orderRepo    *repository.OrderRepository
+	cfg          FleetSummaryConfig
+
+	mu      sync.Mutex
+	entries map[string]*fleetSummaryEntry
+}
+
+type fleetSummaryEntry struct {
+	mu      sync.Mutex
+	summary *models.FleetSummary
+}
+
+func NewFleetSummaryService(agentRepo *repository.AgentRepository, locationRepo *repository.LocationRepository, orderRepo *repository.OrderRepository, cfg FleetSummaryConfig) *FleetSummaryService {
+	return &FleetSummaryService{
+		agentRepo:    agentRepo,
+		locationRepo: locationRepo,
+		orderRepo:    orderRepo,
+		cfg:          cfg,
+		entries:      make(map[string]*fleetSummaryEntry),
+	}
+}
+
+// Summary returns the tenant's fleet summary, rebuilding it if the cached
+// one has expired.
+func (s *FleetSummaryService) Summary(tenantID string) (*models.FleetSummary, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[tenantID]
+	if !ok {
+		entry = &fleetSummaryEntry{}
+		s.entries[tenantID] = entry
+	}
+	s.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.summary != nil && time.Since(entry.summary.GeneratedAt) < s.cfg.CacheTTL {
+		return entry.summary, nil
+	}
+
+	summary, err := s.build(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.summary = summary
+	return summary, nil
+}
+
+func (s *FleetSummaryService) build(tenantID string) (*models.FleetSummary, error) {
+	byStatus, err := s.agentRepo.ForTenant(tenantID).CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	byVehicleType, err := s.agentRepo.ForTenant(tenantID).CountByVehicleType()
+	if err != nil {
+		return nil, err
+	}
+
+	fixes, err := s.locationRepo.ForTenant(tenantID).FindLatestFixes()
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.orderRepo.ForTenant(tenantID).CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summary := &models.FleetSummary{
+		GeneratedAt:   now,
+		ByStatus:      map[string]int64{"available": 0, "busy": 0, "offline": 0},
+		ByVehicleType: byVehicleType,
+		Motion:        map[string]int64{"moving": 0, "idle": 0, "stopped": 0},
+		Locations: models.FleetLocations{
+			StaleAfterSecs: int64(s.cfg.StaleAfter.Seconds()),
+		},
+		Orders: models.FleetOrders{
+			ByStatus: orders,
+		},
+	}
+
+	for status, count := range byStatus {
+		summary.ByStatus[status] = count
+		summary.TotalAgents += count
+	}
+
+	for _, fix := range fixes {
+		switch {
+		case fix.Timestamp == nil:
+			summary.Locations.Missing++
+		case now.Sub(*fix.Timestamp) > s.cfg.StaleAfter:
+			summary.Locations.Stale++
+		default:
+			summary.Locations.Fresh++
+			if fix.MotionState != nil {
+				summary.Motion[*fix.MotionState]++
+			}
+		}
+	}
+
+	summary.Orders.Unassigned = orders[models.OrderCreated]
+	summary.Orders.Active = orders[models.OrderAssigned] + orders[models.OrderPickedUp] + orders[models.OrderInTransit]
+
+	return summary, nil
+}
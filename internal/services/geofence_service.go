@@ -0,0 +1,198 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+// GeofenceService evaluates accepted locations against the active geofences
+// and records enter, exit and dwell events. Active geofences are cached and
+// reloaded after Invalidate.
+type GeofenceService struct {
+	geofenceRepo *repository.GeofenceRepository
+	hub          *realtime.Hub
+
+	mu        sync.RWMutex
+	geofences []models.Geofence
+	loaded    bool
+
+	agentLocks sync.Map
+}
+
+func NewGeofenceService(geofenceRepo *repository.GeofenceRepository, hub *realtime.Hub) *GeofenceService {
+	return &GeofenceService{
+		geofenceRepo: geofenceRepo,
+		hub:          hub,
+	}
+}
+
+func (s *GeofenceService) Invalidate() {
+	s.mu.Lock()
+	s.loaded = false
+	s.geofences = nil
+	s.mu.Unlock()
+}
+
+func (s *GeofenceService) activeGeofences() ([]models.Geofence, error) {
+	s.mu.RLock()
+	if s.loaded {
+		geofences := s.geofences
+		s.mu.RUnlock()
+		return geofences, nil
+	}
+	s.mu.RUnlock()
+
+	geofences, err := s.geofenceRepo.FindAll(true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.geofences = geofences
+	s.loaded = true
+	s.mu.Unlock()
+
+	return geofences, nil
+}
+
+func containsPoint(geofence models.Geofence, lat, lng float64) bool {
+	switch geofence.Shape {
+	case models.GeofenceCircle:
+		return geo.HaversineMeters(geofence.CenterLat, geofence.CenterLng, lat, lng) <= geofence.RadiusM
+	case models.GeofencePolygon:
+		return geo.PointInPolygon(lat, lng, geofence.Vertices)
+	}
+	return false
+}
+
+func (s *GeofenceService) lockAgent(agentID string) func() {
+	value, _ := s.agentLocks.LoadOrStore(agentID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Evaluate compares the location with the agent's previous state for every
+// active geofence and returns the events it recorded. Points older than the
+// last evaluated point for a geofence are ignored so replayed or batched
+// history cannot produce out-of-order transitions.
+func (s *GeofenceService) Evaluate(location models.Location) ([]models.GeofenceEvent, error) {
+	geofences, err := s.activeGeofences()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(geofences) == 0 {
+		return nil, nil
+	}
+
+	unlock := s.lockAgent(location.AgentID)
+	defer unlock()
+
+	stored, err := s.geofenceRepo.FindStates(location.AgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[uint]models.GeofenceState, len(stored))
+	for _, state := range stored {
+		states[state.GeofenceID] = state
+	}
+
+	var changed []models.GeofenceState
+	var events []models.GeofenceEvent
+
+	for _, geofence := range geofences {
+		if geofence.TenantID != location.TenantID {
+			continue
+		}
+
+		state, known := states[geofence.ID]
+		if known && location.Timestamp.Before(state.LastSeenAt) {
+			continue
+		}
+
+		inside := containsPoint(geofence, location.Latitude, location.Longitude)
+
+		if !known {
+			state = models.GeofenceState{
+				AgentID:    location.AgentID,
+				GeofenceID: geofence.ID,
+			}
+		}
+
+		event := models.GeofenceEvent{
+			TenantID:   location.TenantID,
+			GeofenceID: geofence.ID,
+			AgentID:    location.AgentID,
+			Latitude:   location.Latitude,
+			Longitude:  location.Longitude,
+			Timestamp:  location.Timestamp,
+		}
+
+		switch {
+		case inside && !state.Inside:
+			enteredAt := location.Timestamp
+			state.Inside = true
+			state.EnteredAt = &enteredAt
+			state.DwellNotified = false
+			event.EventType = models.GeofenceEnter
+			events = append(events, event)
+
+		case !inside && state.Inside:
+			if state.EnteredAt != nil {
+				event.DwellSecs = int(location.Timestamp.Sub(*state.EnteredAt).Seconds())
+			}
+			state.Inside = false
+			state.EnteredAt = nil
+			state.DwellNotified = false
+			event.EventType = models.GeofenceExit
+			events = append(events, event)
+
+		case inside && state.Inside && !state.DwellNotified && geofence.DwellSeconds > 0 && state.EnteredAt != nil:
+			dwell := location.Timestamp.Sub(*state.EnteredAt)
+			if dwell >= time.Duration(geofence.DwellSeconds)*time.Second {
+				state.DwellNotified = true
+				event.EventType = models.GeofenceDwell
+				event.DwellSecs = int(dwell.Seconds())
+				events = append(events, event)
+			}
+
+		case !inside && !known:
+			// Never been inside; no need to store a row per agent and zone.
+			continue
+		}
+
+		state.LastSeenAt = location.Timestamp
+		changed = append(changed, state)
+	}
+
+	if err := s.geofenceRepo.SaveTransition(changed, events); err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		s.hub.Publish(realtime.Event{
+			Type:     realtime.EventGeofence,
+			TenantID: event.TenantID,
+			AgentID:  event.AgentID,
+			Data: models.GeofenceEventResponse{
+				ID:         event.ID,
+				GeofenceID: event.GeofenceID,
+				AgentID:    event.AgentID,
+				EventType:  event.EventType,
+				Latitude:   event.Latitude,
+				Longitude:  event.Longitude,
+				DwellSecs:  event.DwellSecs,
+				Timestamp:  event.Timestamp,
+			},
+		})
+	}
+
+	return events, nil
+}
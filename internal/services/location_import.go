@@ -0,0 +1,325 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+const MaxImportRows = 100000
+
+var ErrTooManyRows = fmt.Errorf("file has more than %d rows", MaxImportRows)
+
+// ImportRow is one fix read from an import file. A non-empty Error means
+// the row could not be read and is skipped.
+type ImportRow struct {
+	Row     int
+	Request models.LocationRequest
+	Error   string
+}
+
+func DefaultCSVMapping() models.CSVMapping {
+	return models.CSVMapping{
+		Latitude:        "latitude",
+		Longitude:       "longitude",
+		Timestamp:       "timestamp",
+		Speed:           "speed",
+		Heading:         "heading",
+		Accuracy:        "accuracy",
+		PointID:         "point_id",
+		TimestampFormat: "rfc3339",
+		SpeedUnit:       "kmh",
+		Delimiter:       ",",
+	}
+}
+
+// withDefaults fills the unset fields of the mapping from the defaults.
+func withDefaults(mapping models.CSVMapping) models.CSVMapping {
+	defaults := DefaultCSVMapping()
+	fields := []struct{ value, fallback *string }{
+		{&mapping.Latitude, &defaults.Latitude},
+		{&mapping.Longitude, &defaults.Longitude},
+		{&mapping.Timestamp, &defaults.Timestamp},
+		{&mapping.Speed, &defaults.Speed},
+		{&mapping.Heading, &defaults.Heading},
+		{&mapping.Accuracy, &defaults.Accuracy},
+		{&mapping.PointID, &defaults.PointID},
+		{&mapping.TimestampFormat, &defaults.TimestampFormat},
+		{&mapping.SpeedUnit, &defaults.SpeedUnit},
+		{&mapping.Delimiter, &defaults.Delimiter},
+	}
+
+	for _, f := range fields {
+		if strings.TrimSpace(*f.value) == "" {
+			*f.value = *f.fallback
+		}
+	}
+
+	return mapping
+}
+
+func speedFactor(unit string) (float64, bool) {
+	switch strings.ToLower(unit) {
+	case "kmh":
+		return 1, true
+	case "ms":
+		return 3.6, true
+	case "mph":
+		return 1.609344, true
+	default:
+		return 0, false
+	}
+}
+
+func parseImportTime(value, format string) (time.Time, error) {
+	switch strings.ToLower(format) {
+	case "rfc3339":
+		return time.Parse(time.RFC3339, value)
+	case "unix":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMicro(int64(n * 1e6)).UTC(), nil
+	case "unix_ms":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(n).UTC(), nil
+	default:
+		return time.Parse(format, value)
+	}
+}
+
+// ParseLocationCSV reads fixes from a CSV file with a header row, using the
+// mapping to find the columns. Errors in a row only skip that row; an error
+// is returned when the file as a whole cannot be read.
+func ParseLocationCSV(r io.Reader, mapping models.CSVMapping) ([]ImportRow, error) {
+	mapping = withDefaults(mapping)
+
+	factor, ok := speedFactor(mapping.SpeedUnit)
+	if !ok {
+		return nil, errors.New("speed_unit must be: kmh, ms, or mph")
+	}
+
+	delimiter, size := utf8.DecodeRuneInString(mapping.Delimiter)
+	if size != len(mapping.Delimiter) {
+		return nil, errors.New("delimiter must be a single character")
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	column := func(name string) int {
+		if i, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return i
+		}
+		return -1
+	}
+
+	latCol, lngCol, timeCol := column(mapping.Latitude), column(mapping.Longitude), column(mapping.Timestamp)
+	for name, i := range map[string]int{mapping.Latitude: latCol, mapping.Longitude: lngCol, mapping.Timestamp: timeCol} {
+		if i < 0 {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+
+	speedCol, headingCol, accuracyCol, pointCol := column(mapping.Speed), column(mapping.Heading), column(mapping.Accuracy), column(mapping.PointID)
+
+	var rows []ImportRow
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if len(rows) >= MaxImportRows {
+			return nil, ErrTooManyRows
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, ImportRow{Row: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Row: line}
+
+		row.Request, row.Error = csvRequest(record, mapping, factor, latCol, lngCol, timeCol, speedCol, headingCol, accuracyCol, pointCol)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// csvRequest builds the request for one CSV record. A missing speed or
+// heading is reported as -1, the validator's "unknown", so the point is not
+// labelled as stopped.
+func csvRequest(record []string, mapping models.CSVMapping, speedScale float64, latCol, lngCol, timeCol, speedCol, headingCol, accuracyCol, pointCol int) (models.LocationRequest, string) {
+	req := models.LocationRequest{Speed: -1, Heading: -1}
+
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	number := func(i int, name string, target *float64) string {
+		value := cell(i)
+		if value == "" {
+			return ""
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%s %q is not a number", name, value)
+		}
+		*target = n
+		return ""
+	}
+
+	for _, field := range []struct {
+		col    int
+		name   string
+		target *float64
+	}{
+		{latCol, "latitude", &req.Latitude},
+		{lngCol, "longitude", &req.Longitude},
+		{speedCol, "speed", &req.Speed},
+		{headingCol, "heading", &req.Heading},
+		{accuracyCol, "accuracy", &req.Accuracy},
+	} {
+		if msg := number(field.col, field.name, field.target); msg != "" {
+			return req, msg
+		}
+	}
+
+	if cell(latCol) == "" || cell(lngCol) == "" {
+		return req, "latitude and longitude are required"
+	}
+
+	if value := cell(timeCol); value != "" {
+		t, err := parseImportTime(value, mapping.TimestampFormat)
+		if err != nil {
+			return req, fmt.Sprintf("timestamp %q does not match format %s", value, mapping.TimestampFormat)
+		}
+		req.Timestamp = t.UTC().Format(time.RFC3339Nano)
+	}
+
+	if req.Speed > 0 {
+		req.Speed *= speedScale
+	}
+	req.PointID = cell(pointCol)
+
+	return req, ""
+}
+
+type gpxTrackPoint struct {
+	Lat    string   `xml:"lat,attr"`
+	Lon    string   `xml:"lon,attr"`
+	Time   string   `xml:"time"`
+	Speed  *float64 `xml:"speed"`  // GPX 1.0, meters per second
+	Course *float64 `xml:"course"` // GPX 1.0, degrees
+}
+
+// ParseLocationGPX reads the track points of a GPX 1.0 or 1.1 file. Speed
+// and course are only present in GPX 1.0 files.
+func ParseLocationGPX(r io.Reader) ([]ImportRow, error) {
+	decoder := xml.NewDecoder(r)
+
+	var rows []ImportRow
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPX: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "trkpt" {
+			continue
+		}
+
+		if len(rows) >= MaxImportRows {
+			return nil, ErrTooManyRows
+		}
+
+		var point gpxTrackPoint
+		if err := decoder.DecodeElement(&point, &start); err != nil {
+			return nil, fmt.Errorf("invalid GPX: %w", err)
+		}
+
+		row := ImportRow{Row: len(rows) + 1}
+		row.Request, row.Error = gpxRequest(point)
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("GPX file has no track points")
+	}
+
+	return rows, nil
+}
+
+// gpxRequest builds the request for one track point. GPX 1.1 has no speed
+// or course, so they are usually reported as -1, the validator's "unknown".
+func gpxRequest(point gpxTrackPoint) (models.LocationRequest, string) {
+	req := models.LocationRequest{Speed: -1, Heading: -1}
+
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(point.Lat), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(point.Lon), 64)
+	if errLat != nil || errLng != nil {
+		return req, "lat and lon attributes must be numbers"
+	}
+	req.Latitude, req.Longitude = lat, lng
+
+	if value := strings.TrimSpace(point.Time); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			// xsd:dateTime allows omitting the zone; GPX times are UTC.
+			t, err = time.Parse("2006-01-02T15:04:05.999999999", value)
+		}
+		if err != nil {
+			return req, fmt.Sprintf("time %q is not a valid timestamp", value)
+		}
+		req.Timestamp = t.UTC().Format(time.RFC3339Nano)
+	}
+
+	if point.Speed != nil {
+		req.Speed = *point.Speed * 3.6
+	}
+	if point.Course != nil {
+		req.Heading = *point.Course
+	}
+
+	return req, ""
+}
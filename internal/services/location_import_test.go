@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestParseLocationCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping models.CSVMapping
+		want    []ImportRow
+		err     string
+	}{
+		{
+			name: "default columns",
+			input: "latitude,longitude,timestamp,speed,heading,accuracy,point_id\n" +
+				"12.97,77.59,2026-03-01T09:00:00Z,20,90,5,p-1\n" +
+				"12.98,77.60,2026-03-01T09:01:00+05:30,,,,\n",
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: 20, Heading: 90, Accuracy: 5, PointID: "p-1", Timestamp: "2026-03-01T09:00:00Z"}},
+				{Row: 3, Request: models.LocationRequest{Latitude: 12.98, Longitude: 77.60, Speed: -1, Heading: -1, Timestamp: "2026-03-01T03:31:00Z"}},
+			},
+		},
+		{
+			name: "zero speed is kept, a missing one is unknown",
+			input: "latitude,longitude,timestamp,speed,heading\n" +
+				"12.97,77.59,2026-03-01T09:00:00Z,0,0\n" +
+				"12.98,77.60,2026-03-01T09:01:00Z,,\n",
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: 0, Heading: 0, Timestamp: "2026-03-01T09:00:00Z"}},
+				{Row: 3, Request: models.LocationRequest{Latitude: 12.98, Longitude: 77.60, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:01:00Z"}},
+			},
+		},
+		{
+			name:    "mapped columns, unix seconds and m/s",
+			input:   "Lat;Lng;When;V\n12.97;77.59;1772355600.5;10\n",
+			mapping: models.CSVMapping{Latitude: "lat", Longitude: "lng", Timestamp: "when", Speed: "v", TimestampFormat: "unix", SpeedUnit: "ms", Delimiter: ";"},
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: 36, Heading: -1, Timestamp: "2026-03-01T09:00:00.5Z"}},
+			},
+		},
+		{
+			name:    "unix milliseconds and mph",
+			input:   "latitude,longitude,timestamp,speed\n12.97,77.59,1772355600123,10\n",
+			mapping: models.CSVMapping{TimestampFormat: "UNIX_MS", SpeedUnit: "mph"},
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: 16.09344, Heading: -1, Timestamp: "2026-03-01T09:00:00.123Z"}},
+			},
+		},
+		{
+			name:    "go layout",
+			input:   "latitude,longitude,timestamp\n12.97,77.59,01/03/2026 09:00\n",
+			mapping: models.CSVMapping{TimestampFormat: "02/01/2006 15:04"},
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:00:00Z"}},
+			},
+		},
+		{
+			name: "bad rows are reported, not fatal",
+			input: "latitude,longitude,timestamp\n" +
+				"abc,77.59,2026-03-01T09:00:00Z\n" +
+				",77.59,2026-03-01T09:00:00Z\n" +
+				"12.97,77.59,yesterday\n" +
+				"12.97,77.59,\n",
+			want: []ImportRow{
+				{Row: 2, Error: `latitude "abc" is not a number`},
+				{Row: 3, Error: "latitude and longitude are required"},
+				{Row: 4, Error: `timestamp "yesterday" does not match format rfc3339`},
+				{Row: 5, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: -1, Heading: -1}},
+			},
+		},
+		{
+			name:  "row numbers follow quoted line breaks",
+			input: "note,latitude,longitude,timestamp\n\"two\nlines\",12.97,77.59,2026-03-01T09:00:00Z\n\"x\",12.98,77.60,2026-03-01T09:01:00Z\n",
+			want: []ImportRow{
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:00:00Z"}},
+				{Row: 4, Request: models.LocationRequest{Latitude: 12.98, Longitude: 77.60, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:01:00Z"}},
+			},
+		},
+		{
+			name:  "missing required column",
+			input: "latitude,longitude\n12.97,77.59\n",
+			err:   `column "timestamp" not found`,
+		},
+		{
+			name:    "unknown speed unit",
+			input:   "latitude,longitude,timestamp\n",
+			mapping: models.CSVMapping{SpeedUnit: "knots"},
+			err:     "speed_unit must be",
+		},
+		{
+			name:    "multi-character delimiter",
+			input:   "latitude,longitude,timestamp\n",
+			mapping: models.CSVMapping{Delimiter: "||"},
+			err:     "single character",
+		},
+		{
+			name:  "empty file",
+			input: "",
+			err:   "failed to read header row",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseLocationCSV(strings.NewReader(tt.input), tt.mapping)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertImportRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestParseLocationCSVTooManyRows(t *testing.T) {
+	input := "latitude,longitude,timestamp\n" + strings.Repeat("12.97,77.59,2026-03-01T09:00:00Z\n", MaxImportRows+1)
+
+	if _, err := ParseLocationCSV(strings.NewReader(input), models.CSVMapping{}); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("error = %v, want ErrTooManyRows", err)
+	}
+}
+
+func TestParseLocationGPX(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []ImportRow
+		err   string
+	}{
+		{
+			name: "GPX 1.1",
+			input: `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+<trk><trkseg>
+<trkpt lat="12.97" lon="77.59"><time>2026-03-01T09:00:00Z</time></trkpt>
+<trkpt lat="12.98" lon="77.60"><time>2026-03-01T09:01:00</time></trkpt>
+</trkseg></trk></gpx>`,
+			want: []ImportRow{
+				{Row: 1, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:00:00Z"}},
+				{Row: 2, Request: models.LocationRequest{Latitude: 12.98, Longitude: 77.60, Speed: -1, Heading: -1, Timestamp: "2026-03-01T09:01:00Z"}},
+			},
+		},
+		{
+			name: "GPX 1.0 speed and course",
+			input: `<gpx version="1.0"><trk><trkseg>
+<trkpt lat="12.97" lon="77.59"><time>2026-03-01T09:00:00Z</time><speed>5</speed><course>180</course></trkpt>
+</trkseg></trk></gpx>`,
+			want: []ImportRow{
+				{Row: 1, Request: models.LocationRequest{Latitude: 12.97, Longitude: 77.59, Speed: 18, Heading: 180, Timestamp: "2026-03-01T09:00:00Z"}},
+			},
+		},
+		{
+			name: "bad points are reported, not fatal",
+			input: `<gpx><trk><trkseg>
+<trkpt lat="north" lon="77.59"></trkpt>
+<trkpt lat="12.97" lon="77.59"><time>noon</time></trkpt>
+</trkseg></trk></gpx>`,
+			want: []ImportRow{
+				{Row: 1, Error: "lat and lon attributes must be numbers"},
+				{Row: 2, Error: `time "noon" is not a valid timestamp`},
+			},
+		},
+		{
+			name:  "no track points",
+			input: `<gpx><wpt lat="12.97" lon="77.59"/></gpx>`,
+			err:   "no track points",
+		},
+		{
+			name:  "malformed XML",
+			input: `<gpx><trk><trkpt lat="1" lon="2"></trk>`,
+			err:   "invalid GPX",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseLocationGPX(strings.NewReader(tt.input))
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertImportRows(t, rows, tt.want)
+		})
+	}
+}
+
+func assertImportRows(t *testing.T, got, want []ImportRow) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("rows = %d, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Row != w.Row || g.Error != w.Error {
+			t.Errorf("row %d: got (%d, %q), want (%d, %q)", i, g.Row, g.Error, w.Row, w.Error)
+			continue
+		}
+		if w.Error != "" {
+			continue
+		}
+
+		if g.Request.Latitude != w.Request.Latitude || g.Request.Longitude != w.Request.Longitude ||
+			g.Request.Timestamp != w.Request.Timestamp || g.Request.PointID != w.Request.PointID ||
+			g.Request.Heading != w.Request.Heading || g.Request.Accuracy != w.Request.Accuracy ||
+			g.Request.Speed < w.Request.Speed-1e-9 || g.Request.Speed > w.Request.Speed+1e-9 {
+			t.Errorf("row %d: request = %+v, want %+v", i, g.Request, w.Request)
+		}
+	}
+}
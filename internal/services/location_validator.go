@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+type ValidationConfig struct {
+	MaxFutureSkew    time.Duration // Reject fixes stamped further than this in the future
+	MaxAge           time.Duration // Reject fixes older than this; 0 accepts any age
+	MaxAccuracyM     float64       // Reject fixes with a worse reported accuracy
+	LowAccuracyM     float64       // Flag fixes with a worse reported accuracy
+	MaxSpeedKmh      float64       // Reject reported speeds above this
+	MaxImpliedKmh    float64       // Flag fixes that imply a faster jump from the previous fix
+	MinTeleportM     float64       // Jumps shorter than this are never flagged, whatever the time gap
+	ReanchorAfter    int           // Trust this many consecutive suspect fixes that agree with each other over the fix they contradict; 0 never does
+	RequireTimestamp bool          // Reject fixes without a timestamp instead of using server time
+}
+
+func DefaultValidationConfig() ValidationConfig {
+	return ValidationConfig{
+		MaxFutureSkew: 2 * time.Minute,
+		MaxAge:        7 * 24 * time.Hour,
+		MaxAccuracyM:  1000,
+		LowAccuracyM:  50,
+		MaxSpeedKmh:   300,
+		MaxImpliedKmh: 250,
+		MinTeleportM:  500,
+		ReanchorAfter: 3,
+	}
+}
+
+// LocationValidator turns raw location requests into points fit to store.
+// Build rejects points that can never be right; Assess flags points that
+// look wrong next to the agent's previous fix.
+type LocationValidator struct {
+	cfg ValidationConfig
+	now func() time.Time
+}
+
+func NewLocationValidator(cfg ValidationConfig) *LocationValidator {
+	return &LocationValidator{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// ForBackfill returns a validator for importing historical data: the same
+// checks, except that fixes of any age are accepted and every fix must
+// carry its own timestamp.
+func (v *LocationValidator) ForBackfill() *LocationValidator {
+	cfg := v.cfg
+	cfg.MaxAge = 0
+	cfg.RequireTimestamp = true
+
+	return &LocationValidator{
+		cfg: cfg,
+		now: v.now,
+	}
+}
+
+// Build validates the request and returns the location to store. A
+// non-empty reason means the point must be rejected.
+func (v *LocationValidator) Build(req models.LocationRequest) (models.Location, string) {
+	if req.AgentID == "" {
+		return models.Location{}, "agent_id is required"
+	}
+
+	if len(req.PointID) > 64 {
+		return models.Location{}, "point_id must be at most 64 characters"
+	}
+
+	if !isFinite(req.Latitude) || !isFinite(req.Longitude) ||
+		req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+		return models.Location{}, "latitude must be within [-90, 90] and longitude within [-180, 180]"
+	}
+
+	// (0, 0) is what most devices report when they have no fix at all.
+	if req.Latitude == 0 && req.Longitude == 0 {
+		return models.Location{}, "latitude and longitude are required"
+	}
+
+	if !isFinite(req.Speed) || req.Speed > v.cfg.MaxSpeedKmh {
+		return models.Location{}, fmt.Sprintf("speed must not exceed %.0f km/h", v.cfg.MaxSpeedKmh)
+	}
+
+	// Negative speed and heading are how many devices report "unknown".
+	if !isFinite(req.Heading) || req.Heading > 360 {
+		return models.Location{}, "heading must not exceed 360"
+	}
+
+	if !isFinite(req.Accuracy) || req.Accuracy < 0 {
+		return models.Location{}, "accuracy must not be negative"
+	}
+
+	if v.cfg.MaxAccuracyM > 0 && req.Accuracy > v.cfg.MaxAccuracyM {
+		return models.Location{}, fmt.Sprintf("accuracy worse than %.0f m", v.cfg.MaxAccuracyM)
+	}
+
+	now := v.now()
+	timestamp := now
+
+	if req.Timestamp == "" {
+		if v.cfg.RequireTimestamp {
+			return models.Location{}, "timestamp is required"
+		}
+	} else {
+		parsed, err := time.Parse(time.RFC3339, req.Timestamp)
+		if err != nil {
+			return models.Location{}, "timestamp must be in RFC3339 format: 2024-12-07T10:30:00Z"
+		}
+		timestamp = parsed
+	}
+
+	if timestamp.After(now.Add(v.cfg.MaxFutureSkew)) {
+		return models.Location{}, "timestamp is in the future"
+	}
+
+	if v.cfg.MaxAge > 0 && timestamp.Before(now.Add(-v.cfg.MaxAge)) {
+		return models.Location{}, "timestamp is too old"
+	}
+
+	var pointID *string
+	if req.PointID != "" {
+		pointID = &req.PointID
+	}
+
+	location := models.Location{
+		PointID:   pointID,
+		AgentID:   req.AgentID,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Speed:     req.Speed,
+		Heading:   req.Heading,
+		Accuracy:  req.Accuracy,
+		Quality:   models.QualityGood,
+		Timestamp: timestamp,
+	}
+
+	if v.cfg.LowAccuracyM > 0 && req.Accuracy > v.cfg.LowAccuracyM {
+		location.Quality = models.QualityLowAccuracy
+		location.QualityNote = fmt.Sprintf("accuracy %.0f m", req.Accuracy)
+	}
+
+	return location, ""
+}
+
+// Assess compares the location with the agent's previous good fix and flags
+// it as suspect when reaching it would need an impossible speed. Suspect
+// points are still stored so nothing is lost, but consumers such as the
+// distance calculation skip them.
+//
+// suspects are the agent's fixes flagged since previous, oldest first. When
+// the location and the latest of them form a run of ReanchorAfter fixes that
+// each follow plausibly from the one before, it is previous that was wrong:
+// the location is kept good and becomes the reference for the next fixes.
+// Without this a single bad fix accepted as good, such as an agent's first,
+// would get every correct fix after it flagged.
+func (v *LocationValidator) Assess(location *models.Location, previous *models.Location, suspects []models.Location) {
+	note, ok := v.plausible(previous, location)
+	if ok || v.agrees(location, suspects) {
+		return
+	}
+
+	location.Quality = models.QualitySuspect
+	location.QualityNote = note
+}
+
+// SuspectWindow is how many of the agent's latest suspect fixes Assess
+// looks at.
+func (v *LocationValidator) SuspectWindow() int {
+	if v.cfg.ReanchorAfter < 2 {
+		return 0
+	}
+	return v.cfg.ReanchorAfter - 1
+}
+
+// plausible reports whether the location can follow from the earlier fix,
+// and otherwise describes the jump.
+func (v *LocationValidator) plausible(earlier *models.Location, location *models.Location) (string, bool) {
+	if earlier == nil || v.cfg.MaxImpliedKmh <= 0 {
+		return "", true
+	}
+
+	distance := geo.HaversineMeters(earlier.Latitude, earlier.Longitude, location.Latitude, location.Longitude)
+	if distance < v.cfg.MinTeleportM {
+		return "", true
+	}
+
+	elapsed := location.Timestamp.Sub(earlier.Timestamp).Seconds()
+	if elapsed < 1 {
+		elapsed = 1
+	}
+
+	implied := distance / elapsed * 3.6
+	if implied > v.cfg.MaxImpliedKmh {
+		return fmt.Sprintf("jumped %.0f m in %.0f s (%.0f km/h)", distance, elapsed, implied), false
+	}
+
+	return "", true
+}
+
+// agrees reports whether the location and the latest suspects form a run of
+// ReanchorAfter fixes, each plausible next to the one before it.
+func (v *LocationValidator) agrees(location *models.Location, suspects []models.Location) bool {
+	window := v.SuspectWindow()
+	if window == 0 || len(suspects) < window {
+		return false
+	}
+
+	next := location
+	for i := len(suspects) - 1; i >= len(suspects)-window; i-- {
+		if _, ok := v.plausible(&suspects[i], next); !ok {
+			return false
+		}
+		next = &suspects[i]
+	}
+
+	return true
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func testValidator(cfg ValidationConfig, now time.Time) *LocationValidator {
+	v := NewLocationValidator(cfg)
+	v.now = func() time.Time { return now }
+	return v
+}
+
+func TestLocationValidatorBuild(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	valid := func(modify func(*models.LocationRequest)) models.LocationRequest {
+		req := models.LocationRequest{
+			AgentID:   "agent-1",
+			Latitude:  12.9716,
+			Longitude: 77.5946,
+			Speed:     20,
+			Heading:   90,
+			Accuracy:  10,
+			Timestamp: now.Add(-time.Minute).Format(time.RFC3339),
+		}
+		if modify != nil {
+			modify(&req)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		req      models.LocationRequest
+		backfill bool
+		reason   string // Substring of the rejection reason; empty means accepted
+		quality  string
+	}{
+		{name: "valid", req: valid(nil), quality: models.QualityGood},
+		{name: "missing agent", req: valid(func(r *models.LocationRequest) { r.AgentID = "" }), reason: "agent_id is required"},
+		{name: "long point id", req: valid(func(r *models.LocationRequest) { r.PointID = strings.Repeat("p", 65) }), reason: "point_id"},
+		{name: "latitude out of range", req: valid(func(r *models.LocationRequest) { r.Latitude = 91 }), reason: "latitude must be within"},
+		{name: "longitude out of range", req: valid(func(r *models.LocationRequest) { r.Longitude = -181 }), reason: "longitude within"},
+		{name: "NaN latitude", req: valid(func(r *models.LocationRequest) { r.Latitude = math.NaN() }), reason: "latitude must be within"},
+		{name: "null island", req: valid(func(r *models.LocationRequest) { r.Latitude, r.Longitude = 0, 0 }), reason: "are required"},
+		{name: "too fast", req: valid(func(r *models.LocationRequest) { r.Speed = 301 }), reason: "speed must not exceed"},
+		{name: "unknown speed and heading", req: valid(func(r *models.LocationRequest) { r.Speed, r.Heading = -1, -1 }), quality: models.QualityGood},
+		{name: "heading above 360", req: valid(func(r *models.LocationRequest) { r.Heading = 361 }), reason: "heading"},
+		{name: "negative accuracy", req: valid(func(r *models.LocationRequest) { r.Accuracy = -1 }), reason: "accuracy must not be negative"},
+		{name: "accuracy too poor", req: valid(func(r *models.LocationRequest) { r.Accuracy = 1001 }), reason: "accuracy worse than"},
+		{name: "low accuracy", req: valid(func(r *models.LocationRequest) { r.Accuracy = 120 }), quality: models.QualityLowAccuracy},
+		{name: "bad timestamp", req: valid(func(r *models.LocationRequest) { r.Timestamp = "yesterday" }), reason: "RFC3339"},
+		{name: "future timestamp", req: valid(func(r *models.LocationRequest) { r.Timestamp = now.Add(5 * time.Minute).Format(time.RFC3339) }), reason: "in the future"},
+		{name: "within clock skew", req: valid(func(r *models.LocationRequest) { r.Timestamp = now.Add(time.Minute).Format(time.RFC3339) }), quality: models.QualityGood},
+		{name: "too old", req: valid(func(r *models.LocationRequest) { r.Timestamp = now.Add(-8 * 24 * time.Hour).Format(time.RFC3339) }), reason: "too old"},
+		{name: "missing timestamp uses server time", req: valid(func(r *models.LocationRequest) { r.Timestamp = "" }), quality: models.QualityGood},
+		{name: "backfill accepts old fixes", req: valid(func(r *models.LocationRequest) { r.Timestamp = now.AddDate(-1, 0, 0).Format(time.RFC3339) }), backfill: true, quality: models.QualityGood},
+		{name: "backfill requires timestamp", req: valid(func(r *models.LocationRequest) { r.Timestamp = "" }), backfill: true, reason: "timestamp is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := testValidator(DefaultValidationConfig(), now)
+			if tt.backfill {
+				v = v.ForBackfill()
+			}
+
+			location, reason := v.Build(tt.req)
+
+			if tt.reason != "" {
+				if !strings.Contains(reason, tt.reason) {
+					t.Fatalf("reason = %q, want it to contain %q", reason, tt.reason)
+				}
+				return
+			}
+
+			if reason != "" {
+				t.Fatalf("rejected with %q, want accepted", reason)
+			}
+			if location.Quality != tt.quality {
+				t.Errorf("quality = %q, want %q", location.Quality, tt.quality)
+			}
+			if location.AgentID != tt.req.AgentID {
+				t.Errorf("agent = %q, want %q", location.AgentID, tt.req.AgentID)
+			}
+		})
+	}
+}
+
+func TestLocationValidatorBuildTimestamp(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	v := testValidator(DefaultValidationConfig(), now)
+
+	location, reason := v.Build(models.LocationRequest{AgentID: "agent-1", Latitude: 1, Longitude: 1})
+	if reason != "" {
+		t.Fatalf("rejected with %q", reason)
+	}
+	if !location.Timestamp.Equal(now) {
+		t.Errorf("timestamp = %v, want server time %v", location.Timestamp, now)
+	}
+	if location.PointID != nil {
+		t.Errorf("point id = %q, want nil", *location.PointID)
+	}
+}
+
+func TestLocationValidatorAssess(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	previous := &models.Location{Latitude: 12.9716, Longitude: 77.5946, Timestamp: start}
+
+	tests := []struct {
+		name     string
+		previous *models.Location
+		lat, lng float64
+		elapsed  time.Duration
+		suspect  bool
+	}{
+		{name: "no previous fix", previous: nil, lat: 28.6139, lng: 77.2090, elapsed: time.Second},
+		{name: "plausible drive", previous: previous, lat: 12.9816, lng: 77.5946, elapsed: time.Minute},
+		{name: "short jump is never flagged", previous: previous, lat: 12.9756, lng: 77.5946, elapsed: time.Second},
+		{name: "teleport", previous: previous, lat: 28.6139, lng: 77.2090, elapsed: time.Minute, suspect: true},
+		{name: "same timestamp far away", previous: previous, lat: 12.9916, lng: 77.5946, elapsed: 0, suspect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := testValidator(DefaultValidationConfig(), start.Add(time.Hour))
+
+			location := models.Location{
+				Latitude:  tt.lat,
+				Longitude: tt.lng,
+				Quality:   models.QualityGood,
+				Timestamp: start.Add(tt.elapsed),
+			}
+			v.Assess(&location, tt.previous, nil)
+
+			if got := location.Quality == models.QualitySuspect; got != tt.suspect {
+				t.Errorf("suspect = %v (%s), want %v", got, location.QualityNote, tt.suspect)
+			}
+		})
+	}
+}
+
+func TestLocationValidatorAssessReanchors(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	v := testValidator(DefaultValidationConfig(), start.Add(time.Hour))
+
+	fix := func(minute int, lat, lng float64) models.Location {
+		return models.Location{
+			Latitude:  lat,
+			Longitude: lng,
+			Quality:   models.QualityGood,
+			Timestamp: start.Add(time.Duration(minute) * time.Minute),
+		}
+	}
+
+	// The agent's first fix is wrong by ~1,700 km; every fix after it is a
+	// correct one, driving north through Bangalore.
+	track := []struct {
+		fix     models.Location
+		suspect bool
+	}{
+		{fix(0, 28.6139, 77.2090), false}, // Accepted, nothing to compare with
+		{fix(1, 12.9716, 77.5946), true},
+		{fix(2, 12.9766, 77.5946), true},
+		{fix(3, 12.9816, 77.5946), false}, // Third fix in agreement re-anchors
+		{fix(4, 12.9866, 77.5946), false},
+		{fix(5, 28.6139, 77.2090), true}, // A later glitch is still flagged
+		{fix(6, 12.9966, 77.5946), false},
+	}
+
+	var previous *models.Location
+	var suspects []models.Location
+	for i, step := range track {
+		location := step.fix
+		v.Assess(&location, previous, suspects)
+
+		if got := location.Quality == models.QualitySuspect; got != step.suspect {
+			t.Fatalf("fix %d: suspect = %v (%s), want %v", i, got, location.QualityNote, step.suspect)
+		}
+
+		if location.Quality == models.QualitySuspect {
+			suspects = append(suspects, location)
+		} else {
+			previous = &location
+			suspects = nil
+		}
+	}
+}
+
+func TestLocationValidatorAssessNeedsAgreement(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	v := testValidator(DefaultValidationConfig(), start.Add(time.Hour))
+
+	previous := &models.Location{Latitude: 12.9716, Longitude: 77.5946, Timestamp: start}
+
+	// Two suspect fixes that contradict each other do not outvote the
+	// previous good fix.
+	suspects := []models.Location{
+		{Latitude: 28.6139, Longitude: 77.2090, Timestamp: start.Add(time.Minute)},
+		{Latitude: 19.0760, Longitude: 72.8777, Timestamp: start.Add(2 * time.Minute)},
+	}
+
+	location := models.Location{Latitude: 19.0800, Longitude: 72.8777, Quality: models.QualityGood, Timestamp: start.Add(3 * time.Minute)}
+	v.Assess(&location, previous, suspects)
+
+	if location.Quality != models.QualitySuspect {
+		t.Errorf("quality = %q, want suspect", location.Quality)
+	}
+}
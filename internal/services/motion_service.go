@@ -0,0 +1,495 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+// MotionThresholds define the motion states of one vehicle type. Each state
+// boundary has a higher speed to cross it upwards than downwards, so speeds
+// hovering around a boundary do not flip the state.
+type MotionThresholds struct {
+	IdleEnterKmh   float64       // Stopped becomes idle above this
+	IdleExitKmh    float64       // Idle or moving becomes stopped below this
+	MovingEnterKmh float64       // Stopped or idle becomes moving at or above this
+	MovingExitKmh  float64       // Moving drops to idle below this
+	MinDuration    time.Duration // A new state must hold this long before it is adopted
+}
+
+type MotionConfig struct {
+	WindowSize     int           // Fixes the speed estimate is based on, including the newest
+	WindowMaxAge   time.Duration // Fixes older than this before the newest are left out of the window
+	MinDisplaceAge time.Duration // Displacement speed needs the window to span at least this long
+	Default        MotionThresholds
+	ByVehicleType  map[string]MotionThresholds
+}
+
+func DefaultMotionConfig() MotionConfig {
+	twoWheeler := MotionThresholds{
+		IdleEnterKmh:   2,
+		IdleExitKmh:    0.5,
+		MovingEnterKmh: 8,
+		MovingExitKmh:  4,
+		MinDuration:    20 * time.Second,
+	}
+	fourWheeler := MotionThresholds{
+		IdleEnterKmh:   3,
+		IdleExitKmh:    1,
+		MovingEnterKmh: 10,
+		MovingExitKmh:  5,
+		MinDuration:    30 * time.Second,
+	}
+
+	return MotionConfig{
+		WindowSize:     5,
+		WindowMaxAge:   2 * time.Minute,
+		MinDisplaceAge: 10 * time.Second,
+		Default:        twoWheeler,
+		ByVehicleType: map[string]MotionThresholds{
+			"bike":    twoWheeler,
+			"scooter": twoWheeler,
+			"car":     fourWheeler,
+			"truck": {
+				IdleEnterKmh:   3,
+				IdleExitKmh:    1,
+				MovingEnterKmh: 10,
+				MovingExitKmh:  5,
+				MinDuration:    45 * time.Second,
+			},
+		},
+	}
+}
+
+type motionThresholdsJSON struct {
+	IdleEnterKmh   *float64 `json:"idle_enter_kmh"`
+	IdleExitKmh    *float64 `json:"idle_exit_kmh"`
+	MovingEnterKmh *float64 `json:"moving_enter_kmh"`
+	MovingExitKmh  *float64 `json:"moving_exit_kmh"`
+	MinDuration    string   `json:"min_duration"` // Go duration, e.g. "45s"
+}
+
+// ApplyThresholdsJSON overrides thresholds from a JSON object keyed by
+// vehicle type, where "default" applies to vehicle types without an entry:
+//
+//	{"truck": {"moving_enter_kmh": 12, "min_duration": "1m"}}
+//
+// Fields left out keep their current values; a vehicle type without
+// thresholds yet starts from the default ones.
+func (cfg *MotionConfig) ApplyThresholdsJSON(data []byte) error {
+	var overrides map[string]motionThresholdsJSON
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return err
+	}
+
+	byVehicleType := make(map[string]MotionThresholds, len(cfg.ByVehicleType)+len(overrides))
+	for vehicleType, t := range cfg.ByVehicleType {
+		byVehicleType[vehicleType] = t
+	}
+
+	if override, ok := overrides["default"]; ok {
+		t, err := override.apply(cfg.Default)
+		if err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+		cfg.Default = t
+	}
+
+	for vehicleType, override := range overrides {
+		if vehicleType == "default" {
+			continue
+		}
+		vehicleType = strings.ToLower(vehicleType)
+
+		base, ok := byVehicleType[vehicleType]
+		if !ok {
+			base = cfg.Default
+		}
+		t, err := override.apply(base)
+		if err != nil {
+			return fmt.Errorf("%s: %w", vehicleType, err)
+		}
+		byVehicleType[vehicleType] = t
+	}
+
+	cfg.ByVehicleType = byVehicleType
+	return nil
+}
+
+func (o motionThresholdsJSON) apply(t MotionThresholds) (MotionThresholds, error) {
+	for _, field := range []struct {
+		value  *float64
+		target *float64
+	}{
+		{o.IdleEnterKmh, &t.IdleEnterKmh},
+		{o.IdleExitKmh, &t.IdleExitKmh},
+		{o.MovingEnterKmh, &t.MovingEnterKmh},
+		{o.MovingExitKmh, &t.MovingExitKmh},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	if o.MinDuration != "" {
+		d, err := time.ParseDuration(o.MinDuration)
+		if err != nil {
+			return t, fmt.Errorf("min_duration: %w", err)
+		}
+		t.MinDuration = d
+	}
+
+	switch {
+	case t.IdleExitKmh < 0 || t.MinDuration < 0:
+		return t, errors.New("thresholds must not be negative")
+	case t.IdleExitKmh > t.IdleEnterKmh || t.MovingExitKmh > t.MovingEnterKmh:
+		return t, errors.New("exit speeds must not exceed enter speeds")
+	case t.IdleEnterKmh >= t.MovingEnterKmh || t.IdleExitKmh > t.MovingExitKmh:
+		return t, errors.New("idle speeds must be below moving speeds")
+	}
+
+	return t, nil
+}
+
+func (cfg MotionConfig) thresholdsFor(vehicleType string) MotionThresholds {
+	if t, ok := cfg.ByVehicleType[strings.ToLower(vehicleType)]; ok {
+		return t
+	}
+	return cfg.Default
+}
+
+// MotionService keeps a smoothed motion state per agent. Unlike the motion
+// status stored with each fix, which comes from that fix's reported speed
+// alone, the state is based on a window of recent fixes and only changes
+// once the new state has held for the vehicle's minimum duration. Every
+// change is recorded as an event and published.
+type MotionService struct {
+	motionRepo   *repository.MotionRepository
+	locationRepo *repository.LocationRepository
+	agentRepo    *repository.AgentRepository
+	hub          *realtime.Hub
+	cfg          MotionConfig
+
+	agentLocks sync.Map
+}
+
+func NewMotionService(motionRepo *repository.MotionRepository, locationRepo *repository.LocationRepository, agentRepo *repository.AgentRepository, hub *realtime.Hub, cfg MotionConfig) *MotionService {
+	return &MotionService{
+		motionRepo:   motionRepo,
+		locationRepo: locationRepo,
+		agentRepo:    agentRepo,
+		hub:          hub,
+		cfg:          cfg,
+	}
+}
+
+func (s *MotionService) lockAgent(agentID string) func() {
+	value, _ := s.agentLocks.LoadOrStore(agentID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// EvaluateBatch folds newly stored locations, in chronological order, into
+// their agents' motion states and returns the events it recorded. Each
+// agent's state, vehicle type and recent fixes are loaded once per batch.
+// Locations older than the last evaluated one are ignored, and suspect
+// fixes never move the state.
+func (s *MotionService) EvaluateBatch(locations []models.Location) ([]models.MotionEvent, error) {
+	type agentKey struct{ tenantID, agentID string }
+
+	var order []agentKey
+	byAgent := make(map[agentKey][]models.Location)
+	for _, location := range locations {
+		if location.Quality == models.QualitySuspect {
+			continue
+		}
+		key := agentKey{location.TenantID, location.AgentID}
+		if _, ok := byAgent[key]; !ok {
+			order = append(order, key)
+		}
+		byAgent[key] = append(byAgent[key], location)
+	}
+
+	var events []models.MotionEvent
+	var firstErr error
+	for _, key := range order {
+		agentEvents, err := s.evaluateAgent(key.tenantID, key.agentID, byAgent[key])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		events = append(events, agentEvents...)
+	}
+
+	for _, event := range events {
+		s.hub.Publish(realtime.Event{
+			Type:     realtime.EventMotion,
+			TenantID: event.TenantID,
+			AgentID:  event.AgentID,
+			Data:     MotionEventResponse(event),
+		})
+	}
+
+	return events, firstErr
+}
+
+// evaluateAgent folds one agent's locations, oldest first, into its state.
+func (s *MotionService) evaluateAgent(tenantID, agentID string, locations []models.Location) ([]models.MotionEvent, error) {
+	unlock := s.lockAgent(agentID)
+	defer unlock()
+
+	motionRepo := s.motionRepo.ForTenant(tenantID)
+
+	state, err := motionRepo.FindState(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil {
+		fresh := locations[:0:0]
+		for _, location := range locations {
+			if !location.Timestamp.Before(state.LastSeenAt) {
+				fresh = append(fresh, location)
+			}
+		}
+		locations = fresh
+	}
+	if len(locations) == 0 {
+		return nil, nil
+	}
+
+	agent, err := s.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return nil, err
+	}
+	thresholds := s.cfg.thresholdsFor(agent.VehicleType)
+
+	first, last := locations[0], locations[len(locations)-1]
+
+	// One query covers the windows of every location in the batch.
+	recent, err := s.locationRepo.ForTenant(tenantID).
+		FindWindow(agentID, first.Timestamp.Add(-s.cfg.WindowMaxAge), last.Timestamp, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		state = &models.MotionState{
+			AgentID:  agentID,
+			TenantID: tenantID,
+			State:    models.MotionUnknown,
+			Since:    first.Timestamp,
+		}
+	}
+
+	var events []models.MotionEvent
+	for _, location := range locations {
+		window := motionWindow(recent, location, s.cfg.WindowMaxAge, s.cfg.WindowSize)
+		speed, known := EstimateSpeed(window, location, thresholds, s.cfg.MinDisplaceAge)
+
+		if known {
+			state.SpeedKmh = math.Round(speed*100) / 100
+			if event := advanceMotion(state, NextMotionState(state.State, speed, thresholds), location, thresholds); event != nil {
+				events = append(events, *event)
+			}
+		}
+		state.LastSeenAt = location.Timestamp
+	}
+
+	if err := motionRepo.SaveTransition(state, events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// motionWindow picks the location's window out of the agent's recent fixes,
+// which are newest first: up to size fixes at or before it and not older
+// than maxAge.
+func motionWindow(recent []models.Location, location models.Location, maxAge time.Duration, size int) []models.Location {
+	since := location.Timestamp.Add(-maxAge)
+
+	window := make([]models.Location, 0, size)
+	for _, loc := range recent {
+		if loc.Timestamp.After(location.Timestamp) {
+			continue
+		}
+		if loc.Timestamp.Before(since) || len(window) == size {
+			break
+		}
+		window = append(window, loc)
+	}
+
+	return window
+}
+
+// advanceMotion applies the observed target state. A change out of unknown
+// is adopted at once; any other change needs the target to hold for
+// MinDuration, measured from the first fix that saw it.
+func advanceMotion(state *models.MotionState, target string, location models.Location, thresholds MotionThresholds) *models.MotionEvent {
+	if target == state.State {
+		state.Candidate = ""
+		state.CandidateSince = nil
+		return nil
+	}
+
+	changeAt := location.Timestamp
+
+	if state.State != models.MotionUnknown {
+		if state.Candidate != target || state.CandidateSince == nil {
+			since := location.Timestamp
+			state.Candidate = target
+			state.CandidateSince = &since
+			if thresholds.MinDuration > 0 {
+				return nil
+			}
+		}
+
+		if location.Timestamp.Sub(*state.CandidateSince) < thresholds.MinDuration {
+			return nil
+		}
+		changeAt = *state.CandidateSince
+	}
+
+	event := &models.MotionEvent{
+		TenantID:     location.TenantID,
+		AgentID:      location.AgentID,
+		FromState:    state.State,
+		ToState:      target,
+		Latitude:     location.Latitude,
+		Longitude:    location.Longitude,
+		DurationSecs: int(changeAt.Sub(state.Since).Seconds()),
+		Timestamp:    changeAt,
+	}
+
+	state.State = target
+	state.Since = changeAt
+	state.Candidate = ""
+	state.CandidateSince = nil
+
+	return event
+}
+
+// NextMotionState classifies the speed, using the thresholds that apply when
+// leaving the current state.
+func NextMotionState(current string, speed float64, t MotionThresholds) string {
+	switch current {
+	case models.MotionMoving:
+		switch {
+		case speed < t.IdleExitKmh:
+			return models.MotionStopped
+		case speed < t.MovingExitKmh:
+			return models.MotionIdle
+		default:
+			return models.MotionMoving
+		}
+	case models.MotionIdle:
+		switch {
+		case speed >= t.MovingEnterKmh:
+			return models.MotionMoving
+		case speed < t.IdleExitKmh:
+			return models.MotionStopped
+		default:
+			return models.MotionIdle
+		}
+	default:
+		switch {
+		case speed >= t.MovingEnterKmh:
+			return models.MotionMoving
+		case speed > t.IdleEnterKmh:
+			return models.MotionIdle
+		default:
+			return models.MotionStopped
+		}
+	}
+}
+
+// EstimateSpeed returns the agent's speed in km/h over the window, which
+// holds the latest fixes up to and including the current one, newest first.
+// The median reported speed is used, ignoring negative (unknown) readings.
+// The window's straight-line displacement overrides it when the two
+// clearly disagree: a device reporting speed while staying put, or one
+// reporting zero while covering ground. It reports false when neither is
+// available.
+func EstimateSpeed(window []models.Location, current models.Location, t MotionThresholds, minDisplaceAge time.Duration) (float64, bool) {
+	points := make([]models.Location, 0, len(window)+1)
+	points = append(points, current)
+	for _, loc := range window {
+		if loc.ID != current.ID && loc.Quality != models.QualitySuspect {
+			points = append(points, loc)
+		}
+	}
+
+	var speeds []float64
+	for _, loc := range points {
+		if loc.Speed >= 0 {
+			speeds = append(speeds, loc.Speed)
+		}
+	}
+
+	reported, hasReported := median(speeds), len(speeds) > 0
+
+	oldest := points[len(points)-1]
+	elapsed := current.Timestamp.Sub(oldest.Timestamp)
+	hasDisplacement := len(points) > 1 && elapsed >= minDisplaceAge
+
+	var displaced float64
+	if hasDisplacement {
+		meters := geo.HaversineMeters(oldest.Latitude, oldest.Longitude, current.Latitude, current.Longitude)
+		displaced = meters / elapsed.Seconds() * 3.6
+	}
+
+	switch {
+	case !hasReported:
+		return displaced, hasDisplacement
+	case !hasDisplacement:
+		return reported, true
+	case displaced < t.MovingExitKmh && displaced < reported/2:
+		return displaced, true
+	case reported < t.IdleEnterKmh && displaced >= t.MovingEnterKmh:
+		return displaced, true
+	default:
+		return reported, true
+	}
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func MotionEventResponse(event models.MotionEvent) models.MotionEventResponse {
+	return models.MotionEventResponse{
+		ID:           event.ID,
+		AgentID:      event.AgentID,
+		FromState:    event.FromState,
+		ToState:      event.ToState,
+		Latitude:     event.Latitude,
+		Longitude:    event.Longitude,
+		DurationSecs: event.DurationSecs,
+		Timestamp:    event.Timestamp,
+	}
+}
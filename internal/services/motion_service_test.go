@@ -0,0 +1,346 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestNextMotionState(t *testing.T) {
+	th := DefaultMotionConfig().Default // idle 2/0.5, moving 8/4
+
+	tests := []struct {
+		current string
+		speed   float64
+		want    string
+	}{
+		{models.MotionUnknown, 0, models.MotionStopped},
+		{models.MotionUnknown, 5, models.MotionIdle},
+		{models.MotionUnknown, 8, models.MotionMoving},
+		{models.MotionStopped, 1.5, models.MotionStopped},
+		{models.MotionStopped, 2.5, models.MotionIdle},
+		{models.MotionStopped, 9, models.MotionMoving},
+		{models.MotionIdle, 1, models.MotionIdle},
+		{models.MotionIdle, 0.4, models.MotionStopped},
+		{models.MotionIdle, 7.9, models.MotionIdle},
+		{models.MotionIdle, 8, models.MotionMoving},
+		{models.MotionMoving, 5, models.MotionMoving},
+		{models.MotionMoving, 3.9, models.MotionIdle},
+		{models.MotionMoving, 0.2, models.MotionStopped},
+	}
+
+	for _, tt := range tests {
+		if got := NextMotionState(tt.current, tt.speed, th); got != tt.want {
+			t.Errorf("NextMotionState(%s, %.1f) = %s, want %s", tt.current, tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestAdvanceMotion(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	th := MotionThresholds{MinDuration: 20 * time.Second}
+
+	type step struct {
+		after  time.Duration
+		target string
+		event  string // ToState of the expected event; empty means none
+		state  string
+	}
+
+	tests := []struct {
+		name    string
+		initial string
+		steps   []step
+	}{
+		{
+			name:    "leaving unknown is immediate",
+			initial: models.MotionUnknown,
+			steps: []step{
+				{0, models.MotionMoving, models.MotionMoving, models.MotionMoving},
+			},
+		},
+		{
+			name:    "change held long enough",
+			initial: models.MotionStopped,
+			steps: []step{
+				{0, models.MotionMoving, "", models.MotionStopped},
+				{10 * time.Second, models.MotionMoving, "", models.MotionStopped},
+				{20 * time.Second, models.MotionMoving, models.MotionMoving, models.MotionMoving},
+			},
+		},
+		{
+			name:    "brief spike is ignored",
+			initial: models.MotionStopped,
+			steps: []step{
+				{0, models.MotionMoving, "", models.MotionStopped},
+				{10 * time.Second, models.MotionStopped, "", models.MotionStopped},
+				{25 * time.Second, models.MotionMoving, "", models.MotionStopped},
+				{40 * time.Second, models.MotionMoving, "", models.MotionStopped},
+			},
+		},
+		{
+			name:    "new candidate restarts the clock",
+			initial: models.MotionStopped,
+			steps: []step{
+				{0, models.MotionIdle, "", models.MotionStopped},
+				{15 * time.Second, models.MotionMoving, "", models.MotionStopped},
+				{30 * time.Second, models.MotionMoving, "", models.MotionStopped},
+				{35 * time.Second, models.MotionMoving, models.MotionMoving, models.MotionMoving},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &models.MotionState{AgentID: "agent-1", State: tt.initial, Since: start.Add(-time.Hour)}
+
+			for i, s := range tt.steps {
+				location := models.Location{AgentID: "agent-1", Timestamp: start.Add(s.after)}
+				event := advanceMotion(state, s.target, location, th)
+
+				switch {
+				case s.event == "" && event != nil:
+					t.Fatalf("step %d: unexpected event to %s", i, event.ToState)
+				case s.event != "" && event == nil:
+					t.Fatalf("step %d: no event, want one to %s", i, s.event)
+				case event != nil && event.ToState != s.event:
+					t.Fatalf("step %d: event to %s, want %s", i, event.ToState, s.event)
+				}
+
+				if state.State != s.state {
+					t.Fatalf("step %d: state = %s, want %s", i, state.State, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvanceMotionDatesChangeFromCandidate(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	th := MotionThresholds{MinDuration: 20 * time.Second}
+	state := &models.MotionState{State: models.MotionStopped, Since: start.Add(-10 * time.Minute)}
+
+	advanceMotion(state, models.MotionMoving, models.Location{Timestamp: start}, th)
+	event := advanceMotion(state, models.MotionMoving, models.Location{Timestamp: start.Add(30 * time.Second)}, th)
+
+	if event == nil {
+		t.Fatal("no event")
+	}
+	if !event.Timestamp.Equal(start) || !state.Since.Equal(start) {
+		t.Errorf("change at %v (since %v), want %v", event.Timestamp, state.Since, start)
+	}
+	if event.DurationSecs != 600 {
+		t.Errorf("duration = %d s, want 600", event.DurationSecs)
+	}
+	if state.Candidate != "" || state.CandidateSince != nil {
+		t.Errorf("candidate %q left behind", state.Candidate)
+	}
+}
+
+func TestEstimateSpeed(t *testing.T) {
+	th := DefaultMotionConfig().Default
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	fix := func(id uint, ago time.Duration, lat, speed float64) models.Location {
+		return models.Location{ID: id, Latitude: lat, Longitude: 77.59, Speed: speed, Timestamp: now.Add(-ago)}
+	}
+
+	tests := []struct {
+		name    string
+		window  []models.Location
+		current models.Location
+		want    float64
+		known   bool
+	}{
+		{
+			name:    "single fix uses its reported speed",
+			current: fix(1, 0, 12.97, 12),
+			want:    12,
+			known:   true,
+		},
+		{
+			name:    "single fix with unknown speed",
+			current: fix(1, 0, 12.97, -1),
+			known:   false,
+		},
+		{
+			name:    "median of reported speeds",
+			window:  []models.Location{fix(2, 10*time.Second, 12.9703, 30), fix(1, 20*time.Second, 12.97, 10)},
+			current: fix(3, 0, 12.9706, 12),
+			want:    12,
+			known:   true,
+		},
+		{
+			name:    "reported speed while staying put",
+			window:  []models.Location{fix(2, 15*time.Second, 12.97, 20), fix(1, 30*time.Second, 12.97, 20)},
+			current: fix(3, 0, 12.97, 20),
+			want:    0,
+			known:   true,
+		},
+		{
+			name:    "zero reported while covering ground",
+			window:  []models.Location{fix(1, 30*time.Second, 12.97, 0)},
+			current: fix(2, 0, 12.97+0.0025, 0),
+			want:    33.36,
+			known:   true,
+		},
+		{
+			name:    "displacement only when speeds are unknown",
+			window:  []models.Location{fix(1, 30*time.Second, 12.97, -1)},
+			current: fix(2, 0, 12.97+0.0025, -1),
+			want:    33.36,
+			known:   true,
+		},
+		{
+			name:    "window too short for displacement",
+			window:  []models.Location{fix(1, 5*time.Second, 12.97, -1)},
+			current: fix(2, 0, 12.97+0.0025, -1),
+			known:   false,
+		},
+		{
+			name: "suspect fixes are left out",
+			window: []models.Location{
+				{ID: 1, Latitude: 28.61, Longitude: 77.2, Speed: 200, Quality: models.QualitySuspect, Timestamp: now.Add(-20 * time.Second)},
+			},
+			current: fix(2, 0, 12.97, 6),
+			want:    6,
+			known:   true,
+		},
+		{
+			name:    "current fix in the window is not counted twice",
+			window:  []models.Location{fix(2, 0, 12.97, 40), fix(1, 5*time.Second, 12.97, 10)},
+			current: fix(2, 0, 12.97, 40),
+			want:    25,
+			known:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, known := EstimateSpeed(tt.window, tt.current, th, 10*time.Second)
+			if known != tt.known {
+				t.Fatalf("known = %v, want %v", known, tt.known)
+			}
+			if known && (got < tt.want-0.05 || got > tt.want+0.05) {
+				t.Errorf("speed = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMotionWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// Newest first, as the repository returns them.
+	var recent []models.Location
+	for i := 0; i < 8; i++ {
+		recent = append(recent, models.Location{ID: uint(8 - i), Timestamp: now.Add(-time.Duration(i) * 30 * time.Second)})
+	}
+
+	ids := func(window []models.Location) []uint {
+		var out []uint
+		for _, loc := range window {
+			out = append(out, loc.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		at     time.Duration
+		maxAge time.Duration
+		want   []uint
+	}{
+		{"newest fix", 0, 2 * time.Minute, []uint{8, 7, 6}},
+		{"later fixes are skipped", -90 * time.Second, 2 * time.Minute, []uint{5, 4, 3}},
+		{"age limit", -150 * time.Second, 30 * time.Second, []uint{3, 2}},
+		{"oldest fix", -210 * time.Second, 2 * time.Minute, []uint{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := models.Location{Timestamp: now.Add(tt.at)}
+			got := ids(motionWindow(recent, location, tt.maxAge, 3))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("window = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyThresholdsJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, cfg MotionConfig)
+		err   string
+	}{
+		{
+			name:  "partial override keeps the other fields",
+			input: `{"truck": {"moving_enter_kmh": 12, "min_duration": "1m"}}`,
+			check: func(t *testing.T, cfg MotionConfig) {
+				truck := cfg.thresholdsFor("truck")
+				if truck.MovingEnterKmh != 12 || truck.MinDuration != time.Minute || truck.IdleEnterKmh != 3 {
+					t.Errorf("truck = %+v", truck)
+				}
+				if car := cfg.thresholdsFor("car"); car.MovingEnterKmh != 10 {
+					t.Errorf("car changed: %+v", car)
+				}
+			},
+		},
+		{
+			name:  "new vehicle type starts from the overridden default",
+			input: `{"Van": {"min_duration": "40s"}, "default": {"idle_enter_kmh": 2.5}}`,
+			check: func(t *testing.T, cfg MotionConfig) {
+				van := cfg.thresholdsFor("van")
+				if van.MinDuration != 40*time.Second || van.IdleEnterKmh != 2.5 || van.MovingEnterKmh != 8 {
+					t.Errorf("van = %+v", van)
+				}
+				if cfg.thresholdsFor("unknown").IdleEnterKmh != 2.5 {
+					t.Errorf("default = %+v", cfg.Default)
+				}
+			},
+		},
+		{
+			name:  "exit above enter",
+			input: `{"bike": {"moving_exit_kmh": 9}}`,
+			err:   "bike: exit speeds must not exceed enter speeds",
+		},
+		{
+			name:  "idle above moving",
+			input: `{"default": {"idle_enter_kmh": 8}}`,
+			err:   "default: idle speeds must be below moving speeds",
+		},
+		{
+			name:  "bad duration",
+			input: `{"car": {"min_duration": "soon"}}`,
+			err:   "car: min_duration",
+		},
+		{
+			name:  "not JSON",
+			input: `truck=12`,
+			err:   "invalid character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultMotionConfig()
+			err := cfg.ApplyThresholdsJSON([]byte(tt.input))
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/realtime"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+const offlineDetectorActor = "offline-detector"
+
+type OfflineConfig struct {
+	SilenceWindow time.Duration // Agents not heard from for this long go offline; 0 disables detection
+	CheckInterval time.Duration // How often to look for silent agents
+	Statuses      []string      // Statuses that are taken offline when silent
+}
+
+// DefaultOfflineConfig only takes available agents offline. Busy agents are
+// on a delivery and are left to the order flow, which releases them.
+func DefaultOfflineConfig() OfflineConfig {
+	return OfflineConfig{
+		SilenceWindow: 10 * time.Minute,
+		CheckInterval: time.Minute,
+		Statuses:      []string{"available"},
+	}
+}
+
+// OfflineDetector marks agents offline when they stop sending locations
+// and puts them back in their previous status once fresh points arrive.
+// Agents it took offline are remembered in memory, so ingestion only
+// touches the database for agents that actually need to come back.
+type OfflineDetector struct {
+	agentRepo   *repository.AgentRepository
+	historyRepo *repository.StatusHistoryRepository
+	hub         *realtime.Hub
+	cfg         OfflineConfig
+
+	mu      sync.Mutex
+	offline map[string]string // agent ID -> status before it was taken offline
+}
+
+func NewOfflineDetector(agentRepo *repository.AgentRepository, historyRepo *repository.StatusHistoryRepository, hub *realtime.Hub, cfg OfflineConfig) *OfflineDetector {
+	return &OfflineDetector{
+		agentRepo:   agentRepo,
+		historyRepo: historyRepo,
+		hub:         hub,
+		cfg:         cfg,
+		offline:     make(map[string]string),
+	}
+}
+
+func (d *OfflineDetector) Enabled() bool {
+	return d.cfg.SilenceWindow > 0 && d.cfg.CheckInterval > 0
+}
+
+// Run sweeps for silent agents every CheckInterval until ctx is cancelled.
+func (d *OfflineDetector) Run(ctx context.Context) {
+	if !d.Enabled() {
+		log.Println("Offline detection disabled")
+		return
+	}
+
+	if err := d.restore(); err != nil {
+		log.Printf("Failed to restore offline agents: %v", err)
+	}
+
+	log.Printf("Offline detection started (silence window %s, every %s)", d.cfg.SilenceWindow, d.cfg.CheckInterval)
+
+	ticker := time.NewTicker(d.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		d.Sweep()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Offline detection stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// restore reloads the agents this detector took offline before a restart:
+// those whose latest status change it made.
+func (d *OfflineDetector) restore() error {
+	latest, err := d.historyRepo.FindLatestBefore(nil, time.Now())
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, change := range latest {
+		if change.Source == models.StatusSourceSystem && change.Actor == offlineDetectorActor &&
+			change.ToStatus == "offline" {
+			d.offline[change.AgentID] = change.FromStatus
+		}
+	}
+
+	return nil
+}
+
+// Sweep takes every agent that has been silent for longer than the silence
+// window offline.
+func (d *OfflineDetector) Sweep() {
+	agents, err := d.agentRepo.FindSilent(d.cfg.Statuses, time.Now().Add(-d.cfg.SilenceWindow))
+	if err != nil {
+		log.Printf("Failed to look for silent agents: %v", err)
+		return
+	}
+
+	for _, agent := range agents {
+		reason := fmt.Sprintf("no location received for %s, last heard %s",
+			d.cfg.SilenceWindow, agent.LastSeenAt.UTC().Format(time.RFC3339))
+
+		switched, err := d.agentRepo.SwitchStatus(agent.ID, agent.Status, "offline", models.StatusTrigger{
+			Source: models.StatusSourceSystem,
+			Actor:  offlineDetectorActor,
+			Reason: reason,
+		})
+		if err != nil {
+			log.Printf("Failed to mark agent %s offline: %v", agent.ID, err)
+			continue
+		}
+
+		if !switched {
+			continue
+		}
+
+		d.mu.Lock()
+		d.offline[agent.ID] = agent.Status
+		d.mu.Unlock()
+
+		log.Printf("Agent %s marked offline: %s", agent.ID, reason)
+		d.publish(agent.TenantID, agent.ID, agent.Status, "offline")
+	}
+}
+
+// Heartbeat is called for every accepted location. If the agent was taken
+// offline by the detector and the point is fresh, the agent goes back to the
+// status it had before.
+func (d *OfflineDetector) Heartbeat(location models.Location) {
+	if !d.Enabled() || time.Since(location.Timestamp) > d.cfg.SilenceWindow {
+		return
+	}
+
+	d.mu.Lock()
+	previous, ok := d.offline[location.AgentID]
+	if ok {
+		delete(d.offline, location.AgentID)
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	switched, err := d.agentRepo.SwitchStatus(location.AgentID, "offline", previous, models.StatusTrigger{
+		Source: models.StatusSourceSystem,
+		Actor:  offlineDetectorActor,
+		Reason: fmt.Sprintf("location received at %s", location.Timestamp.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		log.Printf("Failed to bring agent %s back online: %v", location.AgentID, err)
+
+		// Keep the agent tracked so the next fresh point retries.
+		d.mu.Lock()
+		if _, tracked := d.offline[location.AgentID]; !tracked {
+			d.offline[location.AgentID] = previous
+		}
+		d.mu.Unlock()
+		return
+	}
+
+	// Someone changed the status in the meantime; theirs wins.
+	if !switched {
+		return
+	}
+
+	log.Printf("Agent %s back to %s after new location", location.AgentID, previous)
+	d.publish(location.TenantID, location.AgentID, "offline", previous)
+}
+
+// Forget stops the detector from bringing the agent back online, for agents
+// that went offline on purpose.
+func (d *OfflineDetector) Forget(agentID string) {
+	d.mu.Lock()
+	delete(d.offline, agentID)
+	d.mu.Unlock()
+}
+
+func (d *OfflineDetector) publish(tenantID, agentID, from, to string) {
+	d.hub.Publish(realtime.Event{
+		Type:     realtime.EventStatus,
+		TenantID: tenantID,
+		AgentID:  agentID,
+		Data: map[string]interface{}{
+			"agent_id":        agentID,
+			"previous_status": from,
+			"status":          to,
+			"source":          models.StatusSourceSystem,
+		},
+	})
+}
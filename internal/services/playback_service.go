@@ -0,0 +1,151 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+type PlaybackOptions struct {
+	ToleranceM     float64       // Douglas-Peucker tolerance; 0 disables simplification
+	Bucket         time.Duration // Keep at most one fix per bucket; 0 disables downsampling
+	Gap            time.Duration // Silences longer than this start a new segment
+	IncludeSuspect bool          // Keep fixes the validator flagged as suspect
+}
+
+// PlaybackService builds replayable routes from the stored fixes.
+type PlaybackService struct {
+	agentRepo    *repository.AgentRepository
+	locationRepo *repository.LocationRepository
+	distanceCfg  DistanceConfig
+}
+
+func NewPlaybackService(agentRepo *repository.AgentRepository, locationRepo *repository.LocationRepository, distanceCfg DistanceConfig) *PlaybackService {
+	return &PlaybackService{
+		agentRepo:    agentRepo,
+		locationRepo: locationRepo,
+		distanceCfg:  distanceCfg,
+	}
+}
+
+// Playback returns the agent's route between from and to, or nil if the
+// agent does not exist in the tenant.
+func (s *PlaybackService) Playback(tenantID, agentID string, from, to time.Time, opts PlaybackOptions) (*models.Playback, error) {
+	agent, err := s.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return nil, err
+	}
+
+	locations, err := s.locationRepo.ForTenant(tenantID).FindByAgentIDAndTimeRange(agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	playback := &models.Playback{
+		AgentID:    agentID,
+		From:       from,
+		To:         to,
+		ToleranceM: opts.ToleranceM,
+		BucketSecs: int(opts.Bucket.Seconds()),
+		GapSecs:    int(opts.Gap.Seconds()),
+		Segments:   []models.PlaybackSegment{},
+	}
+
+	for _, track := range SplitTrack(locations, opts) {
+		segment := buildSegment(track, opts, s.distanceCfg)
+
+		playback.RawPoints += segment.RawPoints
+		playback.ReturnedPoints += len(segment.Points)
+		playback.DistanceKm += segment.DistanceKm
+		playback.Segments = append(playback.Segments, segment)
+	}
+
+	playback.DistanceKm = metersToKm(playback.DistanceKm * 1000)
+
+	return playback, nil
+}
+
+// SplitTrack drops unusable fixes and splits the chronological track into
+// runs without a silence longer than opts.Gap.
+func SplitTrack(locations []models.Location, opts PlaybackOptions) [][]models.Location {
+	var tracks [][]models.Location
+	var current []models.Location
+
+	for _, loc := range locations {
+		if loc.Quality == models.QualitySuspect && !opts.IncludeSuspect {
+			continue
+		}
+
+		if len(current) > 0 && opts.Gap > 0 && loc.Timestamp.Sub(current[len(current)-1].Timestamp) > opts.Gap {
+			tracks = append(tracks, current)
+			current = nil
+		}
+
+		current = append(current, loc)
+	}
+
+	if len(current) > 0 {
+		tracks = append(tracks, current)
+	}
+
+	return tracks
+}
+
+func buildSegment(track []models.Location, opts PlaybackOptions, distanceCfg DistanceConfig) models.PlaybackSegment {
+	first, last := track[0], track[len(track)-1]
+
+	segment := models.PlaybackSegment{
+		StartedAt:    first.Timestamp,
+		EndedAt:      last.Timestamp,
+		DurationSecs: int64(last.Timestamp.Sub(first.Timestamp).Seconds()),
+		DistanceKm:   metersToKm(PathDistance(track, distanceCfg)),
+		RawPoints:    len(track),
+	}
+
+	sampled := downsample(track, opts.Bucket)
+
+	path := make([][2]float64, len(sampled))
+	for i, loc := range sampled {
+		path[i] = [2]float64{loc.Latitude, loc.Longitude}
+	}
+
+	keep := geo.Simplify(path, opts.ToleranceM)
+
+	segment.Points = make([]models.PlaybackPoint, 0, len(keep))
+	for _, i := range keep {
+		loc := sampled[i]
+		segment.Points = append(segment.Points, models.PlaybackPoint{
+			Latitude:  loc.Latitude,
+			Longitude: loc.Longitude,
+			Speed:     loc.Speed,
+			Heading:   loc.Heading,
+			Status:    loc.Status,
+			Timestamp: loc.Timestamp,
+		})
+	}
+
+	return segment
+}
+
+// downsample keeps the first fix of every bucket, counted from the start of
+// the track, and always the last fix so the track ends where it did.
+func downsample(track []models.Location, bucket time.Duration) []models.Location {
+	if bucket <= 0 || len(track) < 3 {
+		return track
+	}
+
+	start := track[0].Timestamp
+	sampled := []models.Location{track[0]}
+	lastBucket := int64(0)
+
+	for _, loc := range track[1 : len(track)-1] {
+		if b := int64(loc.Timestamp.Sub(start) / bucket); b != lastBucket {
+			sampled = append(sampled, loc)
+			lastBucket = b
+		}
+	}
+
+	return append(sampled, track[len(track)-1])
+}
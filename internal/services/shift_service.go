@@ -0,0 +1,198 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+type ShiftConfig struct {
+	ClockInWindow   time.Duration // How long before the planned start clock-in opens
+	LateGrace       time.Duration // Clock-ins later than this after the start count as late
+	EarlyLeaveGrace time.Duration // Clock-outs earlier than this before the end count as leaving early
+	ClockOutGrace   time.Duration // How long after the end a shift without clock-out stays active
+	MaxShiftLength  time.Duration
+}
+
+func DefaultShiftConfig() ShiftConfig {
+	return ShiftConfig{
+		ClockInWindow:   30 * time.Minute,
+		LateGrace:       5 * time.Minute,
+		EarlyLeaveGrace: 5 * time.Minute,
+		ClockOutGrace:   2 * time.Hour,
+		MaxShiftLength:  16 * time.Hour,
+	}
+}
+
+// ShiftService clocks agents in and out of their shifts and compares the
+// schedule with who is actually online.
+type ShiftService struct {
+	shiftRepo       *repository.ShiftRepository
+	agentRepo       *repository.AgentRepository
+	historyRepo     *repository.StatusHistoryRepository
+	offlineDetector *OfflineDetector
+	cfg             ShiftConfig
+}
+
+func NewShiftService(shiftRepo *repository.ShiftRepository, agentRepo *repository.AgentRepository, historyRepo *repository.StatusHistoryRepository, offlineDetector *OfflineDetector, cfg ShiftConfig) *ShiftService {
+	return &ShiftService{
+		shiftRepo:       shiftRepo,
+		agentRepo:       agentRepo,
+		historyRepo:     historyRepo,
+		offlineDetector: offlineDetector,
+		cfg:             cfg,
+	}
+}
+
+func (s *ShiftService) Config() ShiftConfig {
+	return s.cfg
+}
+
+// IsLate reports whether the agent clocked in late, or has not clocked in
+// although the grace period after the start has passed at the given time.
+func (s *ShiftService) IsLate(shift models.Shift, at time.Time) bool {
+	if shift.ClockInAt != nil {
+		return time.Duration(shift.LateSecs)*time.Second > s.cfg.LateGrace
+	}
+	return shift.Status == models.ShiftScheduled && at.After(shift.StartsAt.Add(s.cfg.LateGrace))
+}
+
+// LeftEarly reports whether the agent clocked out before the end of the
+// shift by more than the grace period.
+func (s *ShiftService) LeftEarly(shift models.Shift) bool {
+	return shift.ClockOutAt != nil && time.Duration(shift.EarlyLeaveSecs)*time.Second > s.cfg.EarlyLeaveGrace
+}
+
+// DisplayStatus is the shift's status, reporting scheduled shifts that ended
+// without a clock-in as missed.
+func (s *ShiftService) DisplayStatus(shift models.Shift, at time.Time) string {
+	if shift.Status == models.ShiftScheduled && !at.Before(shift.EndsAt) {
+		return models.ShiftMissed
+	}
+	return shift.Status
+}
+
+// ClockIn starts the agent's current shift, closing an earlier one the
+// agent never clocked out of. It returns the shift and the agent's status
+// before clocking in.
+func (s *ShiftService) ClockIn(tenantID, agentID string, trigger models.StatusTrigger) (*models.Shift, string, error) {
+	return s.shiftRepo.ForTenant(tenantID).ClockIn(agentID, time.Now(), s.cfg.ClockInWindow, s.cfg.ClockOutGrace, trigger)
+}
+
+// ClockOut ends the agent's active shift and takes the agent offline. It
+// returns the shift and the agent's status before clocking out.
+func (s *ShiftService) ClockOut(tenantID, agentID string, trigger models.StatusTrigger) (*models.Shift, string, error) {
+	shift, previous, err := s.shiftRepo.ForTenant(tenantID).ClockOut(agentID, time.Now(), trigger)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The agent went off duty; new points must not bring it back online.
+	s.offlineDetector.Forget(agentID)
+
+	return shift, previous, nil
+}
+
+// Coverage compares the agents scheduled at the given time with the agents
+// that were online then. Agents that were neither are left out.
+func (s *ShiftService) Coverage(tenantID string, at time.Time) (*models.ShiftCoverage, error) {
+	agents, err := s.agentRepo.ForTenant(tenantID).ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	shifts, err := s.shiftRepo.ForTenant(tenantID).FindAt(at)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := s.statusesAt(tenantID, agents, at)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make(map[string]models.Shift, len(shifts))
+	for _, shift := range shifts {
+		if _, ok := scheduled[shift.AgentID]; !ok {
+			scheduled[shift.AgentID] = shift
+		}
+	}
+
+	coverage := &models.ShiftCoverage{
+		At:     at,
+		Agents: make([]models.ShiftCoverageEntry, 0),
+	}
+
+	for _, agent := range agents {
+		if agent.CreatedAt.After(at) {
+			continue
+		}
+
+		status := statuses[agent.ID]
+		online := status != "offline"
+
+		entry := models.ShiftCoverageEntry{
+			AgentID: agent.ID,
+			Name:    agent.Name,
+			Status:  status,
+		}
+
+		shift, ok := scheduled[agent.ID]
+		switch {
+		case ok:
+			shiftID, startsAt, endsAt := shift.ID, shift.StartsAt, shift.EndsAt
+			entry.ShiftID = &shiftID
+			entry.StartsAt = &startsAt
+			entry.EndsAt = &endsAt
+			entry.ClockedIn = shift.ClockedInAt(at)
+			entry.Late = s.IsLate(shift, at)
+
+			coverage.Scheduled++
+			if online {
+				entry.Coverage = models.CoverageOnShift
+				coverage.OnShift++
+			} else {
+				entry.Coverage = models.CoverageAbsent
+				coverage.Absent++
+			}
+		case online && agent.IsActive:
+			entry.Coverage = models.CoverageOffShiftOnline
+			coverage.OffShiftOnline++
+		default:
+			continue
+		}
+
+		coverage.Agents = append(coverage.Agents, entry)
+	}
+
+	return coverage, nil
+}
+
+// statusesAt returns each agent's status at the given time, worked out from
+// the status history the same way as the start of a status report. The
+// current statuses are used as they are when the time is now.
+func (s *ShiftService) statusesAt(tenantID string, agents []models.DeliveryAgent, at time.Time) (map[string]string, error) {
+	now := time.Now()
+	if !at.Before(now.Add(-time.Minute)) {
+		statuses := make(map[string]string, len(agents))
+		for _, agent := range agents {
+			statuses[agent.ID] = agent.Status
+		}
+		return statuses, nil
+	}
+
+	historyRepo := s.historyRepo.ForTenant(tenantID)
+
+	before, err := historyRepo.FindLatestBefore(nil, at)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := historyRepo.FindInRange(nil, at, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return initialStatuses(agents, before, groupByAgent(after)), nil
+}
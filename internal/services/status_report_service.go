@@ -0,0 +1,209 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+var reportStatuses = []string{"available", "busy", "offline"}
+
+// StatusReportService turns the recorded status transitions into time
+// spent in each status over a range.
+type StatusReportService struct {
+	agentRepo   *repository.AgentRepository
+	historyRepo *repository.StatusHistoryRepository
+}
+
+func NewStatusReportService(agentRepo *repository.AgentRepository, historyRepo *repository.StatusHistoryRepository) *StatusReportService {
+	return &StatusReportService{
+		agentRepo:   agentRepo,
+		historyRepo: historyRepo,
+	}
+}
+
+// AgentReport returns the agent's time in status between from and to, or
+// nil if the agent does not exist in the tenant.
+func (s *StatusReportService) AgentReport(tenantID, agentID string, from, to time.Time) (*models.StatusTimeReport, error) {
+	agent, err := s.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return nil, err
+	}
+
+	reports, err := s.build(tenantID, []models.DeliveryAgent{*agent}, []string{agentID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reports[0], nil
+}
+
+// FleetReport returns the time in status of every agent of the tenant,
+// including deactivated ones, and the fleet-wide totals.
+func (s *StatusReportService) FleetReport(tenantID string, from, to time.Time) (*models.FleetStatusTimeReport, error) {
+	agents, err := s.agentRepo.ForTenant(tenantID).ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.build(tenantID, agents, nil, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &models.FleetStatusTimeReport{
+		From:       from,
+		To:         clampToNow(to),
+		StatusSecs: emptyStatusMap(),
+		Agents:     make([]models.StatusTimeReport, 0, len(reports)),
+	}
+
+	for _, report := range reports {
+		if report.TrackedSecs == 0 {
+			continue
+		}
+
+		fleet.AgentCount++
+		fleet.TrackedSecs += report.TrackedSecs
+		fleet.Transitions += report.Transitions
+		for status, secs := range report.StatusSecs {
+			fleet.StatusSecs[status] += secs
+		}
+		fleet.Agents = append(fleet.Agents, report)
+	}
+
+	fleet.StatusPercent = statusPercent(fleet.StatusSecs, fleet.TrackedSecs)
+	return fleet, nil
+}
+
+// build computes a report per agent. The status at the start of the range
+// is the one set by the agent's last transition before it; without one it
+// is the status the first transition in range left, or failing that the
+// agent's current status. Time before the agent was registered is not
+// counted.
+func (s *StatusReportService) build(tenantID string, agents []models.DeliveryAgent, agentIDs []string, from, to time.Time) ([]models.StatusTimeReport, error) {
+	to = clampToNow(to)
+	historyRepo := s.historyRepo.ForTenant(tenantID)
+
+	before, err := historyRepo.FindLatestBefore(agentIDs, from)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := historyRepo.FindInRange(agentIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byAgent := groupByAgent(changes)
+	initial := initialStatuses(agents, before, byAgent)
+
+	reports := make([]models.StatusTimeReport, 0, len(agents))
+	for _, agent := range agents {
+		agentChanges := byAgent[agent.ID]
+		status := initial[agent.ID]
+
+		start := from
+		if agent.CreatedAt.After(start) {
+			start = agent.CreatedAt
+		}
+
+		report := models.StatusTimeReport{
+			AgentID:     agent.ID,
+			From:        from,
+			To:          to,
+			StatusSecs:  emptyStatusMap(),
+			Transitions: len(agentChanges),
+		}
+
+		if to.After(start) {
+			report.TrackedSecs = to.Sub(start).Seconds()
+			addTimeInStatus(report.StatusSecs, status, start, to, agentChanges)
+		}
+
+		report.StatusPercent = statusPercent(report.StatusSecs, report.TrackedSecs)
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// initialStatuses returns the status each agent was in at the start of a
+// range: the one set by its last transition before the range, or the status
+// its first transition in range left, or its current status.
+func initialStatuses(agents []models.DeliveryAgent, before []models.AgentStatusChange, byAgent map[string][]models.AgentStatusChange) map[string]string {
+	latest := make(map[string]string, len(before))
+	for _, change := range before {
+		latest[change.AgentID] = change.ToStatus
+	}
+
+	statuses := make(map[string]string, len(agents))
+	for _, agent := range agents {
+		status, ok := latest[agent.ID]
+		if !ok {
+			if changes := byAgent[agent.ID]; len(changes) > 0 {
+				status = changes[0].FromStatus
+			} else {
+				status = agent.Status
+			}
+		}
+		statuses[agent.ID] = status
+	}
+
+	return statuses
+}
+
+func groupByAgent(changes []models.AgentStatusChange) map[string][]models.AgentStatusChange {
+	byAgent := make(map[string][]models.AgentStatusChange)
+	for _, change := range changes {
+		byAgent[change.AgentID] = append(byAgent[change.AgentID], change)
+	}
+	return byAgent
+}
+
+// addTimeInStatus walks the chronological transitions from start to end and
+// adds the seconds spent in each status to secs.
+func addTimeInStatus(secs map[string]float64, status string, start, end time.Time, changes []models.AgentStatusChange) {
+	cursor := start
+
+	for _, change := range changes {
+		if change.Timestamp.After(cursor) {
+			secs[status] += change.Timestamp.Sub(cursor).Seconds()
+			cursor = change.Timestamp
+		}
+		status = change.ToStatus
+	}
+
+	if end.After(cursor) {
+		secs[status] += end.Sub(cursor).Seconds()
+	}
+}
+
+func emptyStatusMap() map[string]float64 {
+	m := make(map[string]float64, len(reportStatuses))
+	for _, status := range reportStatuses {
+		m[status] = 0
+	}
+	return m
+}
+
+func statusPercent(secs map[string]float64, total float64) map[string]float64 {
+	percent := make(map[string]float64, len(secs))
+	for status, s := range secs {
+		if total > 0 {
+			percent[status] = math.Round(s/total*10000) / 100
+		} else {
+			percent[status] = 0
+		}
+	}
+	return percent
+}
+
+func clampToNow(t time.Time) time.Time {
+	if now := time.Now(); t.After(now) {
+		return now
+	}
+	return t
+}
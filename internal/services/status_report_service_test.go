@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func TestAddTimeInStatus(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	change := func(after time.Duration, from, to string) models.AgentStatusChange {
+		return models.AgentStatusChange{FromStatus: from, ToStatus: to, Timestamp: start.Add(after)}
+	}
+
+	tests := []struct {
+		name    string
+		status  string
+		changes []models.AgentStatusChange
+		want    map[string]float64
+	}{
+		{
+			name:   "no transitions",
+			status: "available",
+			want:   map[string]float64{"available": 3600},
+		},
+		{
+			name:   "transitions split the range",
+			status: "offline",
+			changes: []models.AgentStatusChange{
+				change(10*time.Minute, "offline", "available"),
+				change(40*time.Minute, "available", "busy"),
+			},
+			want: map[string]float64{"offline": 600, "available": 1800, "busy": 1200},
+		},
+		{
+			name:   "transition at the start",
+			status: "offline",
+			changes: []models.AgentStatusChange{
+				change(0, "offline", "available"),
+			},
+			want: map[string]float64{"available": 3600},
+		},
+		{
+			name:   "transitions with the same timestamp",
+			status: "available",
+			changes: []models.AgentStatusChange{
+				change(30*time.Minute, "available", "busy"),
+				change(30*time.Minute, "busy", "offline"),
+			},
+			want: map[string]float64{"available": 1800, "offline": 1800},
+		},
+		{
+			name:   "transition before the start only sets the status",
+			status: "available",
+			changes: []models.AgentStatusChange{
+				change(-5*time.Minute, "available", "busy"),
+			},
+			want: map[string]float64{"busy": 3600},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secs := emptyStatusMap()
+			addTimeInStatus(secs, tt.status, start, end, tt.changes)
+
+			for _, status := range reportStatuses {
+				if secs[status] != tt.want[status] {
+					t.Errorf("%s = %v, want %v", status, secs[status], tt.want[status])
+				}
+			}
+		})
+	}
+}
+
+func TestInitialStatuses(t *testing.T) {
+	agents := []models.DeliveryAgent{
+		{ID: "a1", Status: "busy"},
+		{ID: "a2", Status: "busy"},
+		{ID: "a3", Status: "busy"},
+	}
+	before := []models.AgentStatusChange{{AgentID: "a1", FromStatus: "offline", ToStatus: "available"}}
+	after := groupByAgent([]models.AgentStatusChange{
+		{AgentID: "a1", FromStatus: "available", ToStatus: "busy"},
+		{AgentID: "a2", FromStatus: "offline", ToStatus: "available"},
+		{AgentID: "a2", FromStatus: "available", ToStatus: "busy"},
+	})
+
+	got := initialStatuses(agents, before, after)
+
+	want := map[string]string{
+		"a1": "available", // Last transition before
+		"a2": "offline",   // First transition after
+		"a3": "busy",      // Current status
+	}
+	for agentID, status := range want {
+		if got[agentID] != status {
+			t.Errorf("%s = %q, want %q", agentID, got[agentID], status)
+		}
+	}
+}
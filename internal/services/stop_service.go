@@ -0,0 +1,143 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/geo"
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+type StopConfig struct {
+	RadiusM     float64       // Fixes within this distance of the stop's centroid belong to it
+	MinDuration time.Duration // Shorter stays are not stops
+	MaxGap      time.Duration // A silence longer than this ends the stop, since we cannot tell where the agent was
+}
+
+func DefaultStopConfig() StopConfig {
+	return StopConfig{
+		RadiusM:     50,
+		MinDuration: 5 * time.Minute,
+		MaxGap:      30 * time.Minute,
+	}
+}
+
+// StopService finds where agents stopped from their location history.
+type StopService struct {
+	agentRepo    *repository.AgentRepository
+	locationRepo *repository.LocationRepository
+	cfg          StopConfig
+}
+
+func NewStopService(agentRepo *repository.AgentRepository, locationRepo *repository.LocationRepository, cfg StopConfig) *StopService {
+	return &StopService{
+		agentRepo:    agentRepo,
+		locationRepo: locationRepo,
+		cfg:          cfg,
+	}
+}
+
+func (s *StopService) Config() StopConfig {
+	return s.cfg
+}
+
+// Stops returns the agent's stops between from and to, or nil if the agent
+// does not exist in the tenant. Stops in progress at either end of the
+// window are cut at it.
+func (s *StopService) Stops(tenantID, agentID string, from, to time.Time, cfg StopConfig) (*models.StopReport, error) {
+	agent, err := s.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return nil, err
+	}
+
+	locations, err := s.locationRepo.ForTenant(tenantID).FindByAgentIDAndTimeRange(agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stops := DetectStops(locations, to, cfg)
+
+	report := &models.StopReport{
+		AgentID:         agentID,
+		From:            from,
+		To:              to,
+		RadiusM:         cfg.RadiusM,
+		MinDurationSecs: int64(cfg.MinDuration.Seconds()),
+		Count:           len(stops),
+		Stops:           stops,
+	}
+
+	for _, stop := range stops {
+		report.TotalDwellSecs += stop.DurationSecs
+		if stop.DurationSecs > report.LongestSecs {
+			report.LongestSecs = stop.DurationSecs
+		}
+	}
+
+	return report, nil
+}
+
+// DetectStops finds runs of consecutive fixes that stay within cfg.RadiusM
+// of their running centroid for at least cfg.MinDuration. The fixes must be
+// in chronological order; suspect fixes are ignored. A stop reaching the
+// last fix is ongoing only if that fix is within cfg.MaxGap of to, the end
+// of the window; after a longer silence we cannot tell where the agent is.
+func DetectStops(locations []models.Location, to time.Time, cfg StopConfig) []models.Stop {
+	points := make([]models.Location, 0, len(locations))
+	for _, loc := range locations {
+		if loc.Quality != models.QualitySuspect {
+			points = append(points, loc)
+		}
+	}
+
+	stops := []models.Stop{}
+
+	for i := 0; i < len(points); {
+		latSum, lngSum := points[i].Latitude, points[i].Longitude
+		j := i + 1
+
+		for ; j < len(points); j++ {
+			if cfg.MaxGap > 0 && points[j].Timestamp.Sub(points[j-1].Timestamp) > cfg.MaxGap {
+				break
+			}
+
+			n := float64(j - i)
+			if geo.HaversineMeters(latSum/n, lngSum/n, points[j].Latitude, points[j].Longitude) > cfg.RadiusM {
+				break
+			}
+
+			latSum += points[j].Latitude
+			lngSum += points[j].Longitude
+		}
+
+		arrived, departed := points[i].Timestamp, points[j-1].Timestamp
+		if departed.Sub(arrived) < cfg.MinDuration {
+			i++
+			continue
+		}
+
+		n := float64(j - i)
+		stop := models.Stop{
+			Latitude:     latSum / n,
+			Longitude:    lngSum / n,
+			ArrivedAt:    arrived,
+			DepartedAt:   departed,
+			DurationSecs: int64(departed.Sub(arrived).Seconds()),
+			PointCount:   j - i,
+			Ongoing:      j == len(points) && (cfg.MaxGap <= 0 || to.Sub(departed) <= cfg.MaxGap),
+		}
+
+		for _, p := range points[i:j] {
+			stop.SpreadM = math.Max(stop.SpreadM, geo.HaversineMeters(stop.Latitude, stop.Longitude, p.Latitude, p.Longitude))
+		}
+		stop.SpreadM = math.Round(stop.SpreadM*10) / 10
+		stop.Latitude = math.Round(stop.Latitude*1e7) / 1e7
+		stop.Longitude = math.Round(stop.Longitude*1e7) / 1e7
+
+		stops = append(stops, stop)
+		i = j
+	}
+
+	return stops
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+// track builds fixes from [meters north of a fixed origin, minutes after
+// start] pairs.
+func track(start time.Time, points ...[2]float64) []models.Location {
+	const metersPerDegree = 111195.0
+
+	locations := make([]models.Location, 0, len(points))
+	for i, p := range points {
+		locations = append(locations, models.Location{
+			ID:        uint(i + 1),
+			Latitude:  12.97 + p[0]/metersPerDegree,
+			Longitude: 77.59,
+			Quality:   models.QualityGood,
+			Timestamp: start.Add(time.Duration(p[1] * float64(time.Minute))),
+		})
+	}
+	return locations
+}
+
+func TestDetectStops(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	cfg := StopConfig{RadiusM: 50, MinDuration: 5 * time.Minute, MaxGap: 30 * time.Minute}
+
+	type wantStop struct {
+		arrivedMin  float64
+		departedMin float64
+		points      int
+		ongoing     bool
+	}
+
+	tests := []struct {
+		name      string
+		locations []models.Location
+		toMin     float64 // End of the window, minutes after start
+		want      []wantStop
+	}{
+		{
+			name:      "no fixes",
+			locations: nil,
+		},
+		{
+			name:      "always moving",
+			locations: track(start, [2]float64{0, 0}, [2]float64{500, 1}, [2]float64{1000, 2}, [2]float64{1500, 3}, [2]float64{2000, 4}, [2]float64{2500, 5}, [2]float64{3000, 6}),
+		},
+		{
+			name: "stop between drives",
+			locations: track(start,
+				[2]float64{-1000, 0},
+				[2]float64{0, 2}, [2]float64{10, 4}, [2]float64{-5, 6}, [2]float64{15, 8},
+				[2]float64{1000, 10},
+			),
+			want: []wantStop{{arrivedMin: 2, departedMin: 8, points: 4}},
+		},
+		{
+			name: "too short to count",
+			locations: track(start,
+				[2]float64{0, 0}, [2]float64{10, 2}, [2]float64{5, 4},
+				[2]float64{1000, 6},
+			),
+		},
+		{
+			name: "ongoing at the end",
+			locations: track(start,
+				[2]float64{-1000, 0},
+				[2]float64{0, 1}, [2]float64{5, 3}, [2]float64{10, 6},
+			),
+			toMin: 20,
+			want:  []wantStop{{arrivedMin: 1, departedMin: 6, points: 3, ongoing: true}},
+		},
+		{
+			name: "stop followed by silence",
+			locations: track(start,
+				[2]float64{-1000, 0},
+				[2]float64{0, 1}, [2]float64{5, 3}, [2]float64{10, 6},
+			),
+			toMin: 180,
+			want:  []wantStop{{arrivedMin: 1, departedMin: 6, points: 3}},
+		},
+		{
+			name: "gap splits the stop",
+			locations: track(start,
+				[2]float64{0, 0}, [2]float64{5, 6},
+				[2]float64{5, 60}, [2]float64{0, 62},
+			),
+			want: []wantStop{{arrivedMin: 0, departedMin: 6, points: 2}},
+		},
+		{
+			name: "two stops",
+			locations: track(start,
+				[2]float64{0, 0}, [2]float64{10, 5},
+				[2]float64{2000, 10}, [2]float64{2010, 15}, [2]float64{2005, 20},
+				[2]float64{5000, 25},
+			),
+			want: []wantStop{
+				{arrivedMin: 0, departedMin: 5, points: 2},
+				{arrivedMin: 10, departedMin: 20, points: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := start.Add(time.Duration(tt.toMin * float64(time.Minute)))
+			stops := DetectStops(tt.locations, to, cfg)
+
+			if len(stops) != len(tt.want) {
+				t.Fatalf("stops = %d, want %d: %+v", len(stops), len(tt.want), stops)
+			}
+
+			for i, want := range tt.want {
+				got := stops[i]
+				arrived := start.Add(time.Duration(want.arrivedMin * float64(time.Minute)))
+				departed := start.Add(time.Duration(want.departedMin * float64(time.Minute)))
+
+				if !got.ArrivedAt.Equal(arrived) || !got.DepartedAt.Equal(departed) {
+					t.Errorf("stop %d: %v - %v, want %v - %v", i, got.ArrivedAt, got.DepartedAt, arrived, departed)
+				}
+				if got.PointCount != want.points {
+					t.Errorf("stop %d: points = %d, want %d", i, got.PointCount, want.points)
+				}
+				if got.Ongoing != want.ongoing {
+					t.Errorf("stop %d: ongoing = %v, want %v", i, got.Ongoing, want.ongoing)
+				}
+				if got.DurationSecs != int64(departed.Sub(arrived).Seconds()) {
+					t.Errorf("stop %d: duration = %d s", i, got.DurationSecs)
+				}
+				if got.SpreadM > cfg.RadiusM {
+					t.Errorf("stop %d: spread %.1f m exceeds the radius", i, got.SpreadM)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectStopsIgnoresSuspectFixes(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	cfg := StopConfig{RadiusM: 50, MinDuration: 5 * time.Minute}
+
+	locations := track(start, [2]float64{0, 0}, [2]float64{5000, 3}, [2]float64{10, 6})
+	locations[1].Quality = models.QualitySuspect
+
+	stops := DetectStops(locations, start.Add(10*time.Minute), cfg)
+	if len(stops) != 1 || stops[0].PointCount != 2 {
+		t.Fatalf("stops = %+v, want one stop of two fixes", stops)
+	}
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+	"github.com/Naitik-ag/fleetintel-backend/internal/repository"
+)
+
+const exportChunkSize = 5000
+
+type TrackFormat string
+
+const (
+	TrackGeoJSON TrackFormat = "geojson"
+	TrackGPX     TrackFormat = "gpx"
+	TrackKML     TrackFormat = "kml"
+)
+
+func ParseTrackFormat(value string) (TrackFormat, bool) {
+	switch format := TrackFormat(value); format {
+	case TrackGeoJSON, TrackGPX, TrackKML:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+func (f TrackFormat) ContentType() string {
+	switch f {
+	case TrackGPX:
+		return "application/gpx+xml"
+	case TrackKML:
+		return "application/vnd.google-earth.kml+xml"
+	default:
+		return "application/geo+json"
+	}
+}
+
+func (f TrackFormat) Extension() string {
+	return string(f)
+}
+
+// TrackExport is an agent's track over a range, read from the database in
+// chunks while it is written out.
+type TrackExport struct {
+	locationRepo *repository.LocationRepository
+	agent        models.DeliveryAgent
+	from, to     time.Time
+	first        []models.Location
+}
+
+// HasPoints reports whether the range holds any fixes.
+func (e *TrackExport) HasPoints() bool {
+	return len(e.first) > 0
+}
+
+// single reports whether the range holds exactly one fix. A first chunk
+// shorter than a full chunk is the whole track.
+func (e *TrackExport) single() bool {
+	return len(e.first) == 1
+}
+
+// TrackExportService renders stored tracks as GeoJSON, GPX or KML.
+type TrackExportService struct {
+	agentRepo    *repository.AgentRepository
+	locationRepo *repository.LocationRepository
+}
+
+func NewTrackExportService(agentRepo *repository.AgentRepository, locationRepo *repository.LocationRepository) *TrackExportService {
+	return &TrackExportService{
+		agentRepo:    agentRepo,
+		locationRepo: locationRepo,
+	}
+}
+
+// Open prepares the export of the agent's fixes between from and to, or
+// returns nil if the agent does not exist in the tenant. The first chunk is
+// read up front so an empty range can be reported before streaming starts.
+func (s *TrackExportService) Open(tenantID, agentID string, from, to time.Time) (*TrackExport, error) {
+	agent, err := s.agentRepo.ForTenant(tenantID).FindByID(agentID)
+	if err != nil || agent == nil {
+		return nil, err
+	}
+
+	locationRepo := s.locationRepo.ForTenant(tenantID)
+
+	first, err := locationRepo.FindRangeAfter(agentID, from, 0, to, exportChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrackExport{
+		locationRepo: locationRepo,
+		agent:        *agent,
+		from:         from,
+		to:           to,
+		first:        first,
+	}, nil
+}
+
+// WriteTo streams the track in the given format, flushing after every
+// chunk. It returns the number of points written.
+func (e *TrackExport) WriteTo(w *bufio.Writer, format TrackFormat) (int, error) {
+	var enc trackEncoder
+	switch format {
+	case TrackGPX:
+		enc = &gpxEncoder{w: w}
+	case TrackKML:
+		enc = &kmlEncoder{w: w}
+	default:
+		enc = &geoJSONEncoder{w: w}
+	}
+
+	if err := enc.begin(e); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	chunk := e.first
+	for len(chunk) > 0 {
+		for _, loc := range chunk {
+			if err := enc.point(loc, count == 0); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if err := w.Flush(); err != nil {
+			return count, err
+		}
+
+		if len(chunk) < exportChunkSize {
+			break
+		}
+
+		last := chunk[len(chunk)-1]
+
+		var err error
+		chunk, err = e.locationRepo.FindRangeAfter(e.agent.ID, last.Timestamp, last.ID, e.to, exportChunkSize)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	if err := enc.end(e, count); err != nil {
+		return count, err
+	}
+
+	return count, w.Flush()
+}
+
+type trackEncoder interface {
+	begin(e *TrackExport) error
+	point(loc models.Location, first bool) error
+	end(e *TrackExport, count int) error
+}
+
+func formatCoord(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func escapeXML(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// geoJSONEncoder writes a FeatureCollection holding one LineString feature,
+// or a Point feature when the track has a single fix, since a LineString
+// needs two positions. The geometry comes before the properties so the
+// point count is known by the time they are written.
+type geoJSONEncoder struct {
+	w      *bufio.Writer
+	single bool
+}
+
+func (g *geoJSONEncoder) begin(e *TrackExport) error {
+	g.single = e.single()
+
+	geometry := `{"type":"LineString","coordinates":[`
+	if g.single {
+		geometry = `{"type":"Point","coordinates":`
+	}
+
+	_, err := g.w.WriteString(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":` + geometry)
+	return err
+}
+
+func (g *geoJSONEncoder) point(loc models.Location, first bool) error {
+	if !first {
+		g.w.WriteByte(',')
+	}
+	_, err := fmt.Fprintf(g.w, "[%s,%s]", formatCoord(loc.Longitude), formatCoord(loc.Latitude))
+	return err
+}
+
+func (g *geoJSONEncoder) end(e *TrackExport, count int) error {
+	properties, err := json.Marshal(map[string]interface{}{
+		"agent_id":    e.agent.ID,
+		"name":        e.agent.Name,
+		"from":        e.from.UTC(),
+		"to":          e.to.UTC(),
+		"point_count": count,
+	})
+	if err != nil {
+		return err
+	}
+
+	closing := "]}"
+	if g.single {
+		closing = "}"
+	}
+
+	_, err = fmt.Fprintf(g.w, `%s,"properties":%s}]}`, closing, properties)
+	return err
+}
+
+// gpxEncoder writes a GPX 1.1 document with a single track.
+type gpxEncoder struct {
+	w *bufio.Writer
+}
+
+func (g *gpxEncoder) begin(e *TrackExport) error {
+	_, err := fmt.Fprintf(g.w, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="FleetIntel" xmlns="http://www.topografix.com/GPX/1/1">
+<metadata><name>%s</name><time>%s</time></metadata>
+<trk><name>%s</name><trkseg>
+`, escapeXML(e.agent.Name), time.Now().UTC().Format(time.RFC3339), escapeXML(e.agent.ID))
+	return err
+}
+
+func (g *gpxEncoder) point(loc models.Location, first bool) error {
+	_, err := fmt.Fprintf(g.w, "<trkpt lat=\"%s\" lon=\"%s\"><time>%s</time></trkpt>\n",
+		formatCoord(loc.Latitude), formatCoord(loc.Longitude), loc.Timestamp.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (g *gpxEncoder) end(e *TrackExport, count int) error {
+	_, err := g.w.WriteString("</trkseg></trk>\n</gpx>\n")
+	return err
+}
+
+// kmlEncoder writes a KML document with the track as a LineString
+// placemark, or a Point placemark when the track has a single fix.
+type kmlEncoder struct {
+	w        *bufio.Writer
+	geometry string
+}
+
+func (k *kmlEncoder) begin(e *TrackExport) error {
+	k.geometry = "LineString"
+	geometry := "<LineString><tessellate>1</tessellate>"
+	if e.single() {
+		k.geometry = "Point"
+		geometry = "<Point>"
+	}
+
+	_, err := fmt.Fprintf(k.w, `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document><name>%s</name>
+<Placemark><name>%s</name>
+<TimeSpan><begin>%s</begin><end>%s</end></TimeSpan>
+%s<coordinates>
+`, escapeXML(e.agent.Name), escapeXML(e.agent.ID),
+		e.from.UTC().Format(time.RFC3339), e.to.UTC().Format(time.RFC3339), geometry)
+	return err
+}
+
+func (k *kmlEncoder) point(loc models.Location, first bool) error {
+	_, err := fmt.Fprintf(k.w, "%s,%s\n", formatCoord(loc.Longitude), formatCoord(loc.Latitude))
+	return err
+}
+
+func (k *kmlEncoder) end(e *TrackExport, count int) error {
+	_, err := fmt.Fprintf(k.w, "</coordinates></%s>\n</Placemark>\n</Document>\n</kml>\n", k.geometry)
+	return err
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Naitik-ag/fleetintel-backend/internal/models"
+)
+
+func testTrackExport(points int) *TrackExport {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	first := make([]models.Location, points)
+	for i := range first {
+		first[i] = models.Location{
+			ID:        uint(i + 1),
+			Latitude:  12.97 + float64(i)*0.001,
+			Longitude: 77.59,
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	return &TrackExport{
+		agent: models.DeliveryAgent{ID: "agent-1", Name: `Ravi "R" & Co`},
+		from:  start,
+		to:    start.Add(time.Hour),
+		first: first,
+	}
+}
+
+func writeTrack(t *testing.T, e *TrackExport, format TrackFormat) string {
+	t.Helper()
+
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+
+	count, err := e.WriteTo(w, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(e.first) {
+		t.Errorf("count = %d, want %d", count, len(e.first))
+	}
+
+	return b.String()
+}
+
+func TestTrackExportGeoJSON(t *testing.T) {
+	tests := []struct {
+		points   int
+		geometry string
+	}{
+		{points: 1, geometry: "Point"},
+		{points: 2, geometry: "LineString"},
+		{points: 3, geometry: "LineString"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.geometry, func(t *testing.T) {
+			out := writeTrack(t, testTrackExport(tt.points), TrackGeoJSON)
+
+			var doc struct {
+				Type     string
+				Features []struct {
+					Geometry struct {
+						Type        string
+						Coordinates json.RawMessage
+					}
+					Properties map[string]interface{}
+				}
+			}
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("invalid JSON: %v\n%s", err, out)
+			}
+
+			if len(doc.Features) != 1 {
+				t.Fatalf("features = %d, want 1", len(doc.Features))
+			}
+			feature := doc.Features[0]
+
+			if feature.Geometry.Type != tt.geometry {
+				t.Errorf("geometry = %q, want %q", feature.Geometry.Type, tt.geometry)
+			}
+
+			if tt.geometry == "Point" {
+				var position []float64
+				if err := json.Unmarshal(feature.Geometry.Coordinates, &position); err != nil || len(position) != 2 {
+					t.Errorf("point coordinates = %s, want one position", feature.Geometry.Coordinates)
+				}
+			} else {
+				var positions [][]float64
+				if err := json.Unmarshal(feature.Geometry.Coordinates, &positions); err != nil || len(positions) != tt.points {
+					t.Errorf("line coordinates = %s, want %d positions", feature.Geometry.Coordinates, tt.points)
+				}
+			}
+
+			if feature.Properties["name"] != `Ravi "R" & Co` || feature.Properties["point_count"] != float64(tt.points) {
+				t.Errorf("properties = %v", feature.Properties)
+			}
+		})
+	}
+}
+
+func TestTrackExportXML(t *testing.T) {
+	tests := []struct {
+		format TrackFormat
+		points int
+		want   string
+		absent string
+	}{
+		{format: TrackKML, points: 1, want: "<Point><coordinates>", absent: "LineString"},
+		{format: TrackKML, points: 2, want: "<LineString><tessellate>1</tessellate><coordinates>", absent: "<Point>"},
+		{format: TrackGPX, points: 1, want: "<trkpt", absent: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.format, tt.points), func(t *testing.T) {
+			out := writeTrack(t, testTrackExport(tt.points), tt.format)
+
+			decoder := xml.NewDecoder(strings.NewReader(out))
+			for {
+				if _, err := decoder.Token(); err != nil {
+					if err != io.EOF {
+						t.Fatalf("invalid XML: %v\n%s", err, out)
+					}
+					break
+				}
+			}
+
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, out)
+			}
+			if tt.absent != "" && strings.Contains(out, tt.absent) {
+				t.Errorf("output contains %q:\n%s", tt.absent, out)
+			}
+			if got := strings.Count(out, "77.59"); got != tt.points {
+				t.Errorf("positions = %d, want %d", got, tt.points)
+			}
+		})
+	}
+}